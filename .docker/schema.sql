@@ -20,22 +20,45 @@ CREATE TABLE IF NOT EXISTS player_events (
     content         jsonb,
     created_on      timestamp with time zone            DEFAULT now(),
     vector          varchar(9192),
-    version         bigint          NOT NULL
+    version         bigint          NOT NULL,
+    tx_id           bigint          NOT NULL DEFAULT txid_current()
 );
 
 CREATE INDEX IF NOT EXISTS player_event_actor_idx on player_events(actor_id);
+CREATE UNIQUE INDEX IF NOT EXISTS player_event_version_idx on player_events(actor_id, version);
+CREATE INDEX IF NOT EXISTS player_event_position_idx on player_events(tx_id, id);
 
 CREATE TABLE IF NOT EXISTS player_id_map (
     id                      uuid        PRIMARY KEY,
     identifiers             jsonb       NOT NULL,
     actor_id                uuid        NOT NULL,
     starting_on             timestamp with time zone 	DEFAULT now(),
+    ending_on               timestamp with time zone,
+    retired                 bool        NOT NULL DEFAULT(false),
     UNIQUE(identifiers, actor_id)
 );
 
 CREATE INDEX IF NOT EXISTS player_id_map_actor_idx on player_id_map(actor_id);
 CREATE INDEX IF NOT EXISTS player_id_map_ids_idx on player_id_map(identifiers);
 
+CREATE TABLE IF NOT EXISTS player_keys (
+    actor_id        uuid            PRIMARY KEY,
+    key             bytea           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS player_outbox (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now(),
+    attempts        int             NOT NULL DEFAULT 0,
+    next_attempt_on timestamp with time zone            DEFAULT now(),
+    published_on    timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS player_outbox_pending_idx on player_outbox(next_attempt_on) WHERE published_on IS NULL;
+
 CREATE TABLE IF NOT EXISTS player_snapshots (
 	id								uuid	        PRIMARY KEY,
     actor_id                        uuid            NOT NULL,
@@ -45,7 +68,8 @@ CREATE TABLE IF NOT EXISTS player_snapshots (
 	last_event_id					uuid 	        NOT NULL,
     last_event_applied_on			timestamp with time zone  		NOT NULL,
 	vector							varchar(9192),
-	version							bigint 			NOT NULL
+	version							bigint 			NOT NULL,
+	superseded_on					timestamp with time zone
 );
 
 CREATE INDEX IF NOT EXISTS player_snapshot_actor_idx on player_snapshots(actor_id);
@@ -67,16 +91,21 @@ CREATE TABLE IF NOT EXISTS motorist_events (
     content         jsonb,
     created_on      timestamp with time zone            DEFAULT now(),
     vector          varchar(9192),
-    version         bigint          NOT NULL
+    version         bigint          NOT NULL,
+    tx_id           bigint          NOT NULL DEFAULT txid_current()
 );
 
 CREATE INDEX IF NOT EXISTS motorist_event_actor_idx on motorist_events(actor_id);
+CREATE UNIQUE INDEX IF NOT EXISTS motorist_event_version_idx on motorist_events(actor_id, version);
+CREATE INDEX IF NOT EXISTS motorist_event_position_idx on motorist_events(tx_id, id);
 
 CREATE TABLE IF NOT EXISTS motorist_id_map (
     id                      uuid        PRIMARY KEY,
     identifiers             jsonb       NOT NULL,
     actor_id                uuid        NOT NULL,
     starting_on             timestamp with time zone 	DEFAULT now(),
+    ending_on               timestamp with time zone,
+    retired                 bool        NOT NULL DEFAULT(false),
     UNIQUE(identifiers, actor_id)
 );
 
@@ -91,11 +120,30 @@ CREATE TABLE IF NOT EXISTS motorist_links (
     child_id                uuid            NOT NULL,
     active                  bool            DEFAULT(true),
     starting_on             timestamp with time zone 	DEFAULT now(),
-    UNIQUE(parent_id, child_id)
+    ending_on               timestamp with time zone
 );
 
 CREATE INDEX IF NOT EXISTS motorist_link_parent_idx on motorist_links(parent_id);
 CREATE INDEX IF NOT EXISTS motorist_link_child_idx on motorist_links(child_id);
+CREATE UNIQUE INDEX IF NOT EXISTS motorist_link_active_idx on motorist_links(parent_id, child_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS motorist_keys (
+    actor_id        uuid            PRIMARY KEY,
+    key             bytea           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS motorist_outbox (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now(),
+    attempts        int             NOT NULL DEFAULT 0,
+    next_attempt_on timestamp with time zone            DEFAULT now(),
+    published_on    timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS motorist_outbox_pending_idx on motorist_outbox(next_attempt_on) WHERE published_on IS NULL;
 
 CREATE TABLE IF NOT EXISTS motorist_snapshots (
 	id								uuid	        PRIMARY KEY,
@@ -106,7 +154,8 @@ CREATE TABLE IF NOT EXISTS motorist_snapshots (
 	last_event_id					uuid 	        NOT NULL,
     last_event_applied_on			timestamp with time zone  		NOT NULL,
 	vector							varchar(9192),
-	version							bigint 			NOT NULL
+	version							bigint 			NOT NULL,
+	superseded_on					timestamp with time zone
 );
 
 CREATE INDEX IF NOT EXISTS motorist_snapshot_actor_idx on motorist_snapshots(actor_id);
@@ -128,16 +177,21 @@ CREATE TABLE IF NOT EXISTS vehicle_events (
     content         jsonb,
     created_on      timestamp with time zone            DEFAULT now(),
     vector          varchar(9192),
-    version         bigint          NOT NULL
+    version         bigint          NOT NULL,
+    tx_id           bigint          NOT NULL DEFAULT txid_current()
 );
 
 CREATE INDEX IF NOT EXISTS vehicle_event_actor_idx on vehicle_events(actor_id);
+CREATE UNIQUE INDEX IF NOT EXISTS vehicle_event_version_idx on vehicle_events(actor_id, version);
+CREATE INDEX IF NOT EXISTS vehicle_event_position_idx on vehicle_events(tx_id, id);
 
 CREATE TABLE IF NOT EXISTS vehicle_id_map (
     id                      uuid        PRIMARY KEY,
     identifiers             jsonb       NOT NULL,
     actor_id                uuid        NOT NULL,
     starting_on             timestamp with time zone 	DEFAULT now(),
+    ending_on               timestamp with time zone,
+    retired                 bool        NOT NULL DEFAULT(false),
     UNIQUE(identifiers, actor_id)
 );
 
@@ -152,11 +206,30 @@ CREATE TABLE IF NOT EXISTS vehicle_links (
     child_id                uuid            NOT NULL,
     active                  bool            DEFAULT(true),
     starting_on             timestamp with time zone 	DEFAULT now(),
-    UNIQUE(parent_id, child_id)
+    ending_on               timestamp with time zone
 );
 
 CREATE INDEX IF NOT EXISTS vehicle_link_parent_idx on vehicle_links(parent_id);
 CREATE INDEX IF NOT EXISTS vehicle_link_child_idx on vehicle_links(child_id);
+CREATE UNIQUE INDEX IF NOT EXISTS vehicle_link_active_idx on vehicle_links(parent_id, child_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS vehicle_keys (
+    actor_id        uuid            PRIMARY KEY,
+    key             bytea           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_outbox (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now(),
+    attempts        int             NOT NULL DEFAULT 0,
+    next_attempt_on timestamp with time zone            DEFAULT now(),
+    published_on    timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_outbox_pending_idx on vehicle_outbox(next_attempt_on) WHERE published_on IS NULL;
 
 CREATE TABLE IF NOT EXISTS vehicle_snapshots (
 	id								uuid	        PRIMARY KEY,
@@ -167,7 +240,79 @@ CREATE TABLE IF NOT EXISTS vehicle_snapshots (
 	last_event_id					uuid 	        NOT NULL,
     last_event_applied_on			timestamp with time zone  		NOT NULL,
 	vector							varchar(9192),
-	version							bigint 			NOT NULL
+	version							bigint 			NOT NULL,
+	superseded_on					timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_snapshot_actor_idx on vehicle_snapshots(actor_id);
+
+CREATE TABLE IF NOT EXISTS customer_commands (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb,
+    created_on      timestamp with time zone            DEFAULT now(),
+    vector          varchar(9192),
+    version         bigint          NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS customer_command_actor_idx on customer_commands(actor_id);
+
+CREATE TABLE IF NOT EXISTS customer_events (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb,
+    created_on      timestamp with time zone            DEFAULT now(),
+    vector          varchar(9192),
+    version         bigint          NOT NULL,
+    tx_id           bigint          NOT NULL DEFAULT txid_current()
+);
+
+CREATE INDEX IF NOT EXISTS customer_event_actor_idx on customer_events(actor_id);
+CREATE UNIQUE INDEX IF NOT EXISTS customer_event_version_idx on customer_events(actor_id, version);
+CREATE INDEX IF NOT EXISTS customer_event_position_idx on customer_events(tx_id, id);
+
+CREATE TABLE IF NOT EXISTS customer_id_map (
+    id                      uuid        PRIMARY KEY,
+    identifiers             jsonb       NOT NULL,
+    actor_id                uuid        NOT NULL,
+    starting_on             timestamp with time zone 	DEFAULT now(),
+    ending_on               timestamp with time zone,
+    retired                 bool        NOT NULL DEFAULT(false),
+    UNIQUE(identifiers, actor_id)
+);
+
+CREATE INDEX IF NOT EXISTS customer_id_map_actor_idx on customer_id_map(actor_id);
+CREATE INDEX IF NOT EXISTS customer_id_map_ids_idx on customer_id_map(identifiers);
+
+CREATE TABLE IF NOT EXISTS customer_keys (
+    actor_id        uuid            PRIMARY KEY,
+    key             bytea           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS customer_outbox (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now(),
+    attempts        int             NOT NULL DEFAULT 0,
+    next_attempt_on timestamp with time zone            DEFAULT now(),
+    published_on    timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS customer_outbox_pending_idx on customer_outbox(next_attempt_on) WHERE published_on IS NULL;
+
+CREATE TABLE IF NOT EXISTS customer_snapshots (
+	id								uuid	        PRIMARY KEY,
+    actor_id                        uuid            NOT NULL,
+	content							jsonb 			NOT NULL,
+	last_command_id					uuid  	        NOT NULL,
+	last_command_handled_on			timestamp with time zone  		NOT NULL,
+	last_event_id					uuid 	        NOT NULL,
+    last_event_applied_on			timestamp with time zone  		NOT NULL,
+	vector							varchar(9192),
+	version							bigint 			NOT NULL,
+	superseded_on					timestamp with time zone
 );
 
-CREATE INDEX IF NOT EXISTS vehicle_snapshot_actor_idx on vehicle_snapshots(actor_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS customer_snapshot_actor_idx on customer_snapshots(actor_id);
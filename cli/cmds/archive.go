@@ -0,0 +1,91 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/legitbiz/spry/postgres"
+	"github.com/legitbiz/spry/storage"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [actors...] --output [file]",
+	Short: "Write every record stored for the actor types as newline-delimited JSON",
+	Long: "Writes the id maps, links, commands, events and current snapshots of each actor type. The actor " +
+		"names are Go type names, e.g. Player. Encryption keys aren't exported, so personal data in the " +
+		"archive only decrypts where its keys are already stored.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: exportRecords,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Store the records of an archive written by export, skipping the ones already stored",
+	Long: "Reads an archive from the file, or from stdin when no file is given. The schema of each actor " +
+		"type in the archive must be migrated first. Records are matched by id, so importing the same " +
+		"archive again is safe.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: importRecords,
+}
+
+func GetExport() cobra.Command {
+	exportCmd.Flags().StringP("output", "o", "", "File to write the archive to, stdout when not given")
+	addConnectionFlag(exportCmd.Flags())
+	return *exportCmd
+}
+
+func GetImport() cobra.Command {
+	addConnectionFlag(importCmd.Flags())
+	return *importCmd
+}
+
+func exportRecords(cmd *cobra.Command, args []string) error {
+	var output, _ = cmd.Flags().GetString("output")
+	connection, err := getConnection(cmd)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = cmd.OutOrStdout()
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	store := postgres.CreatePostgresStorage(connection)
+	count, err := storage.Export(cmd.Context(), store, out, args...)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "exported %d records to %s\n", count, output)
+	}
+	return nil
+}
+
+func importRecords(cmd *cobra.Command, args []string) error {
+	connection, err := getConnection(cmd)
+	if err != nil {
+		return err
+	}
+	var in io.Reader = cmd.InOrStdin()
+	if len(args) == 1 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	store := postgres.CreatePostgresStorage(connection)
+	count, err := storage.Import(cmd.Context(), store, in)
+	if err != nil {
+		return fmt.Errorf("failed after %d records: %w", count, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "imported %d records\n", count)
+	return nil
+}
@@ -0,0 +1,279 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// an actor, aggregate, query or process manager type found in Go source
+type DiscoveredActor struct {
+	Name    string
+	Package string
+	// true for types implementing spry.Aggregate
+	IsAggregate bool
+	// true for types implementing spry.Query
+	IsQuery bool
+	// true for types implementing spry.ProcessManager
+	IsProcessManager bool
+	// the child actor types an aggregate names in GetIdentifierSet
+	Children []string
+}
+
+// the parts of `go list -json` used to load packages
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	ImportMap  map[string]string
+	DepOnly    bool
+	Error      *struct{ Err string }
+}
+
+// spry.Identifiers, spry.IdentifierSet and spry.QuerySources are aliases,
+// so the interfaces can be matched without loading the spry package
+var (
+	identifiersType   = types.NewMap(types.Typ[types.String], types.NewInterfaceType(nil, nil))
+	identifierSetType = types.NewMap(types.Typ[types.String], types.NewSlice(identifiersType))
+	querySourcesType  = types.NewMap(types.Typ[types.String], types.NewSlice(types.Typ[types.String]))
+)
+
+// loads the packages matching the patterns, as `go list` understands them,
+// and returns every type implementing spry.Actor, spry.Aggregate,
+// spry.Query or spry.ProcessManager. Commands and events that carry
+// identifiers are left out, as are id types an actor gets its
+// identifiers from by embedding them.
+func DiscoverActors(patterns ...string) ([]DiscoveredActor, error) {
+	packages, err := listPackages(patterns)
+	if err != nil {
+		return nil, err
+	}
+	exports := map[string]string{}
+	imports := map[string]string{}
+	for _, pkg := range packages {
+		exports[pkg.ImportPath] = pkg.Export
+		for path, resolved := range pkg.ImportMap {
+			imports[path] = resolved
+		}
+	}
+	fset := token.NewFileSet()
+	gc := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		if resolved, ok := imports[path]; ok {
+			path = resolved
+		}
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	})
+
+	actors := []DiscoveredActor{}
+	for _, pkg := range packages {
+		if pkg.DepOnly {
+			continue
+		}
+		found, err := discoverInPackage(fset, gc, pkg)
+		if err != nil {
+			return nil, err
+		}
+		actors = append(actors, found...)
+	}
+	sort.SliceStable(actors, func(i, j int) bool {
+		return actors[i].Name < actors[j].Name
+	})
+	return actors, nil
+}
+
+func listPackages(patterns []string) ([]listedPackage, error) {
+	args := append([]string{"list", "-e", "-json", "-export", "-deps"}, patterns...)
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	list := exec.Command("go", args...)
+	list.Stdout = &stdout
+	list.Stderr = &stderr
+	if err := list.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr.String())
+	}
+	packages := []listedPackage{}
+	decoder := json.NewDecoder(&stdout)
+	for {
+		pkg := listedPackage{}
+		err := decoder.Decode(&pkg)
+		if errors.Is(err, io.EOF) {
+			return packages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Error != nil && !pkg.DepOnly {
+			return nil, fmt.Errorf("failed to load %s: %s", pkg.ImportPath, pkg.Error.Err)
+		}
+		packages = append(packages, pkg)
+	}
+}
+
+func discoverInPackage(fset *token.FileSet, importer types.Importer, pkg listedPackage) ([]DiscoveredActor, error) {
+	files := make([]*ast.File, 0, len(pkg.GoFiles))
+	for _, name := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	config := types.Config{Importer: importer}
+	checked, err := config.Check(pkg.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+
+	actors := map[string]*DiscoveredActor{}
+	idTypes := map[string]bool{}
+	scope := checked.Scope()
+	for _, name := range scope.Names() {
+		typeName, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+		named, ok := typeName.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+			continue
+		}
+		methods := types.NewMethodSet(named)
+		// commands and events carry identifiers too
+		if methods.Lookup(checked, "Handle") != nil || methods.Lookup(checked, "Apply") != nil {
+			continue
+		}
+		// a query or process manager may embed the actor it follows
+		if hasMethod(methods, checked, "GetSources", querySourcesType) {
+			isProcess := methods.Lookup(checked, "Correlate") != nil && methods.Lookup(checked, "React") != nil
+			actors[name] = &DiscoveredActor{
+				Name:             name,
+				Package:          pkg.ImportPath,
+				IsQuery:          !isProcess,
+				IsProcessManager: isProcess,
+			}
+			continue
+		}
+		isActor := hasMethod(methods, checked, "GetIdentifiers", identifiersType)
+		isAggregate := hasMethod(methods, checked, "GetIdentifierSet", identifierSetType)
+		if !isActor && !isAggregate {
+			continue
+		}
+		actors[name] = &DiscoveredActor{Name: name, Package: pkg.ImportPath, IsAggregate: isAggregate}
+		for _, method := range []string{"GetIdentifiers", "GetIdentifierSet"} {
+			if source := promotedFrom(named, methods.Lookup(checked, method)); source != "" {
+				idTypes[source] = true
+			}
+		}
+	}
+	for name := range idTypes {
+		if actor, ok := actors[name]; ok && !actor.IsQuery && !actor.IsProcessManager {
+			delete(actors, name)
+		}
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			method, ok := decl.(*ast.FuncDecl)
+			if !ok || method.Recv == nil || method.Name.Name != "GetIdentifierSet" {
+				continue
+			}
+			if actor, ok := actors[receiverName(method.Recv.List[0].Type)]; ok && actor.IsAggregate {
+				actor.Children = childTypes(method, info, actor.Name)
+			}
+		}
+	}
+
+	found := make([]DiscoveredActor, 0, len(actors))
+	for _, actor := range actors {
+		found = append(found, *actor)
+	}
+	return found, nil
+}
+
+func hasMethod(methods *types.MethodSet, pkg *types.Package, name string, result types.Type) bool {
+	selection := methods.Lookup(pkg, name)
+	if selection == nil {
+		return false
+	}
+	signature := selection.Type().(*types.Signature)
+	return signature.Params().Len() == 0 &&
+		signature.Results().Len() == 1 &&
+		types.Identical(signature.Results().At(0).Type(), result)
+}
+
+// the embedded field a method was promoted from, or "" when the type
+// declares the method itself
+func promotedFrom(named *types.Named, selection *types.Selection) string {
+	if selection == nil || len(selection.Index()) < 2 {
+		return ""
+	}
+	structType, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return ""
+	}
+	return structType.Field(selection.Index()[0]).Name()
+}
+
+func receiverName(expr ast.Expr) string {
+	switch receiver := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(receiver.X)
+	case *ast.Ident:
+		return receiver.Name
+	}
+	return ""
+}
+
+// the constant keys of the identifier sets built in GetIdentifierSet,
+// other than the aggregate itself
+func childTypes(method *ast.FuncDecl, info *types.Info, actorName string) []string {
+	children := []string{}
+	ast.Inspect(method.Body, func(node ast.Node) bool {
+		literal, ok := node.(*ast.CompositeLit)
+		if !ok || !types.Identical(info.TypeOf(literal), identifierSetType) {
+			return true
+		}
+		for _, element := range literal.Elts {
+			pair, ok := element.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key := info.Types[pair.Key].Value
+			if key == nil || key.Kind() != constant.String {
+				continue
+			}
+			child := constant.StringVal(key)
+			if child != actorName && !containsName(children, child) {
+				children = append(children, child)
+			}
+		}
+		return true
+	})
+	sort.Strings(children)
+	return children
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/postgres"
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [actor] --id [key=value] --format [json|table]",
+	Short: "Print the snapshot, events, links and commands stored for an actor",
+	Long: "Resolves the identifiers given with --id to an actor and prints its latest snapshot, the events " +
+		"after it, its links and its command log. The actor name is the Go type name, e.g. Player. Use " +
+		"key:=value for identifiers that aren't strings, e.g. --id count:=5.",
+	Args: cobra.ExactArgs(1),
+	RunE: inspectActor,
+}
+
+func GetInspect() cobra.Command {
+	inspectCmd.Flags().StringArray("id", []string{}, "An identifier of the actor as key=value (repeatable)")
+	inspectCmd.Flags().StringP("format", "f", "json", "Output format, json or table")
+	addConnectionFlag(inspectCmd.Flags())
+	return *inspectCmd
+}
+
+// parses key=value pairs into identifiers, decoding key:=value as JSON
+func parseIdentifiers(pairs []string) (spry.Identifiers, error) {
+	ids := spry.Identifiers{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("identifiers are given as key=value, not %s", pair)
+		}
+		if strings.HasSuffix(key, ":") {
+			var decoded any
+			err := json.Unmarshal([]byte(value), &decoded)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not valid JSON: %w", value, err)
+			}
+			ids[strings.TrimSuffix(key, ":")] = decoded
+		} else {
+			ids[key] = value
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("at least one --id is required")
+	}
+	return ids, nil
+}
+
+func inspectActor(cmd *cobra.Command, args []string) error {
+	var actorName = args[0]
+	var pairs, _ = cmd.Flags().GetStringArray("id")
+	var format, _ = cmd.Flags().GetString("format")
+	if format != "json" && format != "table" {
+		return fmt.Errorf("unknown format %s, use json or table", format)
+	}
+	ids, err := parseIdentifiers(pairs)
+	if err != nil {
+		return err
+	}
+	connection, err := getConnection(cmd)
+	if err != nil {
+		return err
+	}
+	inspector, err := postgres.CreateInspector(cmd.Context(), connection)
+	if err != nil {
+		return err
+	}
+	defer inspector.Close()
+
+	inspection, err := inspector.Inspect(cmd.Context(), actorName, ids)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if format == "table" {
+		return printInspection(out, inspection)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(inspection)
+}
+
+// stored records hold their payload under data
+func describeContent(content json.RawMessage) (string, string) {
+	record := struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}{}
+	if json.Unmarshal(content, &record) != nil || record.Data == nil {
+		return "", string(content)
+	}
+	return record.Type, string(record.Data)
+}
+
+func printInspection(out io.Writer, inspection postgres.Inspection) error {
+	table := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	key, _ := spry.IdentifiersToString(inspection.Identifiers)
+	fmt.Fprintf(table, "%s %s\t%s\n", inspection.ActorName, key, inspection.ActorId)
+
+	fmt.Fprintf(table, "\nSNAPSHOT\n")
+	snapshot := struct {
+		Version       uint64          `json:"version"`
+		EventsApplied uint64          `json:"eventsApplied"`
+		Data          json.RawMessage `json:"data"`
+	}{}
+	if json.Unmarshal(inspection.Snapshot, &snapshot) != nil || snapshot.Data == nil {
+		fmt.Fprintf(table, "none\n")
+	} else {
+		fmt.Fprintf(table, "VERSION\tEVENTS APPLIED\tDATA\n")
+		fmt.Fprintf(table, "%d\t%d\t%s\n", snapshot.Version, snapshot.EventsApplied, snapshot.Data)
+	}
+
+	fmt.Fprintf(table, "\nEVENTS SINCE SNAPSHOT\n")
+	fmt.Fprintf(table, "ID\tVERSION\tCREATED ON\tTYPE\tDATA\n")
+	for _, event := range inspection.Events {
+		eventType, data := describeContent(event.Content)
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", event.Id, event.Version, event.CreatedOn.Format("2006-01-02 15:04:05"), eventType, data)
+	}
+
+	if len(inspection.Links) > 0 {
+		fmt.Fprintf(table, "\nLINKS\n")
+		fmt.Fprintf(table, "CHILD\tID\tACTIVE\tENDING ON\n")
+		for _, link := range inspection.Links {
+			endingOn := ""
+			if link.EndingOn != nil {
+				endingOn = link.EndingOn.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(table, "%s\t%s\t%t\t%s\n", link.ChildType, link.ChildId, link.Active, endingOn)
+		}
+	}
+
+	fmt.Fprintf(table, "\nCOMMANDS\n")
+	fmt.Fprintf(table, "ID\tVERSION\tCREATED ON\tTYPE\tDATA\n")
+	for _, command := range inspection.Commands {
+		commandType, data := describeContent(command.Content)
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", command.Id, command.Version, command.CreatedOn.Format("2006-01-02 15:04:05"), commandType, data)
+	}
+	return table.Flush()
+}
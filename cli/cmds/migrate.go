@@ -0,0 +1,141 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/legitbiz/spry/postgres"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const connectionEnv = "SPRY_DATABASE_URL"
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [actors...] --connection [uri]",
+	Short: "Apply the schema migrations each actor doesn't have yet",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  migrateUp,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status [actors...]",
+	Short: "List the schema migrations and when each was applied to each actor",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  migrateStatus,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [actors...] --steps [steps] --destroy-data",
+	Short: "Revert the latest schema migrations applied to each actor",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  migrateDown,
+}
+
+func GetMigrate() cobra.Command {
+	addConnectionFlag(migrateCmd.PersistentFlags())
+	return *migrateCmd
+}
+
+// subcommands are added to the copy of migrate that Init adds to the root
+func GetMigrateSubcommands() []*cobra.Command {
+	migrateDownCmd.Flags().IntP("steps", "s", 1, "Number of migrations to revert for each actor")
+	migrateDownCmd.Flags().Bool("destroy-data", false, "Allow reverting migrations that drop tables holding records")
+	return []*cobra.Command{migrateStatusCmd, migrateDownCmd}
+}
+
+func addConnectionFlag(flags *pflag.FlagSet) {
+	flags.StringP(
+		"connection",
+		"c",
+		"",
+		fmt.Sprintf("Postgres connection URI, read from %s when not given", connectionEnv),
+	)
+}
+
+func getConnection(cmd *cobra.Command) (string, error) {
+	var connection, _ = cmd.Flags().GetString("connection")
+	if connection == "" {
+		connection = os.Getenv(connectionEnv)
+	}
+	if connection == "" {
+		return "", fmt.Errorf("a connection URI is required, use --connection or set %s", connectionEnv)
+	}
+	return connection, nil
+}
+
+func getMigrator(cmd *cobra.Command) (*postgres.Migrator, error) {
+	connection, err := getConnection(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.CreateMigrator(cmd.Context(), connection)
+}
+
+func printMigrations(cmd *cobra.Command, verb string, statuses []postgres.MigrationStatus) {
+	out := cmd.OutOrStdout()
+	if len(statuses) == 0 {
+		fmt.Fprintf(out, "Nothing %s\n", verb)
+	}
+	for _, status := range statuses {
+		fmt.Fprintf(out, "%s %s %04d_%s\n", verb, status.ActorName, status.Version, status.Name)
+	}
+}
+
+func migrateUp(cmd *cobra.Command, args []string) error {
+	migrator, err := getMigrator(cmd)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	applied, err := migrator.Up(cmd.Context(), args...)
+	if err != nil {
+		return err
+	}
+	printMigrations(cmd, "applied", applied)
+	return nil
+}
+
+func migrateStatus(cmd *cobra.Command, args []string) error {
+	migrator, err := getMigrator(cmd)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	statuses, err := migrator.Status(cmd.Context(), args...)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	for _, status := range statuses {
+		appliedOn := "pending"
+		if status.IsApplied() {
+			appliedOn = status.AppliedOn.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%-24s %04d_%-32s %s\n", status.ActorName, status.Version, status.Name, appliedOn)
+	}
+	return nil
+}
+
+func migrateDown(cmd *cobra.Command, args []string) error {
+	var steps, _ = cmd.Flags().GetInt("steps")
+	var destroy, _ = cmd.Flags().GetBool("destroy-data")
+	migrator, err := getMigrator(cmd)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	reverted, err := migrator.Down(cmd.Context(), postgres.MigrateDownOptions{
+		Steps:       steps,
+		DestroyData: destroy,
+	}, args...)
+	if errors.Is(err, postgres.ErrMigrationDestroysData) {
+		return fmt.Errorf("%w; use --destroy-data to revert it anyway", err)
+	}
+	if err != nil {
+		return err
+	}
+	printMigrations(cmd, "reverted", reverted)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/storage"
+	"github.com/spf13/cobra"
+)
+
+// rebuilds the snapshots of one type of actor. The event types an actor
+// applies only exist in the application, so the stock spry binary can't
+// rebuild anything. Applications that want the command register a Rebuild
+// for each of their actor types and add it to the root from their own
+// main, e.g.
+//
+//	cmds.RegisterRebuilder("Player", players.Rebuild)
+//	root := cmds.Init()
+//	rebuild := cmds.GetRebuild()
+//	root.AddCommand(&rebuild)
+//	err := root.Execute()
+type Rebuilder = func(context.Context, storage.RebuildOptions) (storage.RebuildReport, error)
+
+var rebuilders = map[string]Rebuilder{}
+
+func RegisterRebuilder(actorName string, rebuilder Rebuilder) {
+	rebuilders[strings.ToLower(actorName)] = rebuilder
+}
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [actor] --workers [workers] --dry-run",
+	Short: "Replace an actor type's snapshots with ones replayed from its events",
+	Args:  cobra.ExactArgs(1),
+	RunE:  rebuildSnapshots,
+}
+
+func GetRebuild() cobra.Command {
+	rebuildCmd.Flags().IntP("workers", "w", 4, "Number of actors to rebuild at once")
+	rebuildCmd.Flags().Bool("dry-run", false, "Report the actors that would change without writing snapshots")
+	return *rebuildCmd
+}
+
+func rebuildSnapshots(cmd *cobra.Command, args []string) error {
+	var actorName = args[0]
+	rebuilder, ok := rebuilders[strings.ToLower(actorName)]
+	if !ok {
+		if len(rebuilders) == 0 {
+			return fmt.Errorf("no rebuilder is registered for %s; register one with RegisterRebuilder", actorName)
+		}
+		registered := make([]string, 0, len(rebuilders))
+		for name := range rebuilders {
+			registered = append(registered, name)
+		}
+		sort.Strings(registered)
+		return fmt.Errorf(
+			"no rebuilder is registered for %s (registered: %s)",
+			actorName,
+			strings.Join(registered, ", "),
+		)
+	}
+	var workers, _ = cmd.Flags().GetInt("workers")
+	var dryRun, _ = cmd.Flags().GetBool("dry-run")
+
+	out := cmd.OutOrStdout()
+	// workers report progress concurrently
+	lock := sync.Mutex{}
+	report, err := rebuilder(cmd.Context(), storage.RebuildOptions{
+		Workers: workers,
+		DryRun:  dryRun,
+		Progress: func(done int, total int) {
+			lock.Lock()
+			defer lock.Unlock()
+			fmt.Fprintf(out, "\r%s: %d/%d", actorName, done, total)
+		},
+	})
+	if report.Rebuilt > 0 {
+		fmt.Fprintln(out)
+	}
+	if err != nil {
+		return err
+	}
+
+	verb := "changed"
+	if dryRun {
+		verb = "would change"
+	}
+	fmt.Fprintf(out, "%d %s actors replayed, %d %s\n", report.Rebuilt, actorName, len(report.Changed), verb)
+	for _, ids := range report.Changed {
+		key, _ := spry.IdentifiersToString(ids)
+		fmt.Fprintf(out, "  %s\n", key)
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// the rebuild command isn't included since it needs the application's
+// rebuilders; see GetRebuild
 func Init() cobra.Command {
 	var rootCmd = cobra.Command{
 		Use:   "spry",
@@ -12,5 +14,14 @@ func Init() cobra.Command {
 	}
 	var schemaCmd = GetActorSchema()
 	rootCmd.AddCommand(&schemaCmd)
+	var migrateCmd = GetMigrate()
+	migrateCmd.AddCommand(GetMigrateSubcommands()...)
+	rootCmd.AddCommand(&migrateCmd)
+	var inspectCmd = GetInspect()
+	rootCmd.AddCommand(&inspectCmd)
+	var exportCmd = GetExport()
+	rootCmd.AddCommand(&exportCmd)
+	var importCmd = GetImport()
+	rootCmd.AddCommand(&importCmd)
 	return rootCmd
 }
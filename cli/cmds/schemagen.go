@@ -4,33 +4,73 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/arobson/spry/postgres"
+	"github.com/legitbiz/spry/postgres"
 	"github.com/spf13/cobra"
 )
 
 var schemaCmd = &cobra.Command{
-	Use:   "schema [actor] --output [output]",
+	Use:   "schema [actor] --package [packages] --output [output]",
 	Short: "Output the generated schema from a model into a file",
-	Args:  cobra.ExactArgs(1),
-	Run:   generateSchema,
+	Long: "Generates the schema for the actor named, or with --package for every actor, aggregate, query and " +
+		"process manager found in the Go packages, e.g. --package ./..., along with the child actors each " +
+		"aggregate links to.",
+	Args: cobra.MaximumNArgs(1),
+	Run:  generateSchema,
 }
 
 func GetActorSchema() cobra.Command {
 	schemaCmd.Flags().StringP("output", "o", "", "Output path for the generated schema")
+	schemaCmd.Flags().StringSliceP("package", "p", []string{}, "Go packages to find actors in")
 	return *schemaCmd
 }
 
+// the actors, queries and process managers in the packages followed by
+// the children of their aggregates that aren't actors themselves
+func discoverActorNames(patterns []string) []string {
+	actors, err := DiscoverActors(patterns...)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load packages: %s", err))
+	}
+	names := []string{}
+	for _, actor := range actors {
+		if !containsName(names, actor.Name) {
+			names = append(names, actor.Name)
+		}
+	}
+	for _, actor := range actors {
+		for _, child := range actor.Children {
+			if !containsName(names, child) {
+				names = append(names, child)
+			}
+		}
+	}
+	return names
+}
+
 func generateSchema(cmd *cobra.Command, args []string) {
-	var actorName = args[0]
+	var patterns, _ = cmd.Flags().GetStringSlice("package")
+	var actorName string
+	var actorNames []string
+	if len(args) == 1 {
+		actorName = args[0]
+		actorNames = []string{actorName}
+	} else if len(patterns) > 0 {
+		actorName = "schema"
+		actorNames = discoverActorNames(patterns)
+		if len(actorNames) == 0 {
+			panic(fmt.Sprintf("No actors were found in %s", strings.Join(patterns, ", ")))
+		}
+	}
 	if actorName == "" {
-		panic("actor name is required to generate a schema")
+		panic("actor name or --package is required to generate a schema")
 	}
 	var fullPath, _ = cmd.Flags().GetString("output")
 	if fullPath == "" {
 		fmt.Printf("No output path specified, printing to stdout\n")
 	}
-	var schema, err = postgres.PostgresGenerateActorSchema(actorName)
+	var schema, err = postgres.PostgresGenerateSchema(actorNames...)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to generate schema: %e", err))
 	}
@@ -0,0 +1,344 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/storage"
+)
+
+// exports and imports the records held by the other in-memory stores
+type InMemoryArchiveStore struct {
+	Commands  *InMemoryCommandStore
+	Events    *InMemoryEventStore
+	Maps      *InMemoryMapStore
+	Snapshots *InMemorySnapshotStore
+}
+
+func (store *InMemoryArchiveStore) Export(ctx context.Context, actorName string, write func(storage.ArchiveRecord) error) error {
+	actorIds, err := store.Maps.GetActorIds(ctx, actorName)
+	if err != nil {
+		return err
+	}
+	records := []storage.ArchiveRecord{}
+	add := func(kind string, record any) error {
+		archived, err := storage.NewArchiveRecord(kind, actorName, record)
+		records = append(records, archived)
+		return err
+	}
+
+	for _, id := range store.Maps.idRecords(actorIds) {
+		if err := add(storage.ArchivedId, id); err != nil {
+			return err
+		}
+	}
+	for _, link := range store.Maps.linkRecords(actorName) {
+		if err := add(storage.ArchivedLink, link); err != nil {
+			return err
+		}
+	}
+	for _, command := range store.Commands.forActors(actorIds) {
+		if err := add(storage.ArchivedCommand, command); err != nil {
+			return err
+		}
+	}
+	events, err := store.Events.FetchAllSince(ctx, actorName, uuid.Nil, 0, storage.TypeMap{})
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := add(storage.ArchivedEvent, event); err != nil {
+			return err
+		}
+	}
+	for _, snapshot := range store.Snapshots.ofType(actorName) {
+		if err := add(storage.ArchivedSnapshot, snapshot); err != nil {
+			return err
+		}
+	}
+
+	// records are only written once the stores' locks are released
+	for _, record := range records {
+		if err := write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *InMemoryArchiveStore) Import(ctx context.Context, record storage.ArchiveRecord, types storage.TypeMap) error {
+	switch record.Kind {
+	case storage.ArchivedId:
+		id, err := spry.FromJson[storage.IdRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.Maps.importId(ctx, record.ActorName, id)
+	case storage.ArchivedLink:
+		link, err := spry.FromJson[storage.LinkRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.Maps.importLink(ctx, link)
+	case storage.ArchivedCommand:
+		command, err := spry.FromJson[storage.CommandRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		command, err = types.DecodeCommand(command)
+		if err != nil {
+			return err
+		}
+		return store.Commands.importCommand(ctx, record.ActorName, command)
+	case storage.ArchivedEvent:
+		event, err := spry.FromJson[storage.EventRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		// the in-memory store holds events as their registered types
+		event, err = types.DecodeEvent(event)
+		if err != nil {
+			return err
+		}
+		return store.Events.importEvent(ctx, event)
+	case storage.ArchivedSnapshot:
+		snapshot, err := spry.FromJson[storage.Snapshot](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.Snapshots.importSnapshot(ctx, record.ActorName, snapshot)
+	}
+	return fmt.Errorf("%s is not a kind of archived record", record.Kind)
+}
+
+func (maps *InMemoryMapStore) idRecords(actorIds []uuid.UUID) []storage.IdRecord {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	records := []storage.IdRecord{}
+	for _, actorId := range actorIds {
+		for _, history := range maps.history[actorId] {
+			key, _ := spry.IdentifiersToString(history.Identifiers)
+			records = append(records, storage.IdRecord{
+				Id:               maps.entryIds[actorId][key],
+				ActorId:          actorId,
+				IdentifierRecord: history,
+			})
+		}
+	}
+	return records
+}
+
+func (maps *InMemoryMapStore) linkRecords(parentType string) []storage.LinkRecord {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	records := []storage.LinkRecord{}
+	for parentId, children := range maps.LinkMap[parentType] {
+		for childType, childIds := range children {
+			for _, childId := range childIds {
+				started := maps.started[parentId][childId]
+				records = append(records, storage.LinkRecord{
+					Id:         started.id,
+					ParentType: parentType,
+					ParentId:   parentId,
+					ChildType:  childType,
+					ChildId:    childId,
+					StartingOn: started.startingOn,
+				})
+			}
+		}
+	}
+	for _, period := range maps.ended {
+		if period.parentType != parentType {
+			continue
+		}
+		records = append(records, storage.LinkRecord{
+			Id:         period.id,
+			ParentType: parentType,
+			ParentId:   period.parentId,
+			ChildType:  period.childType,
+			ChildId:    period.childId,
+			StartingOn: period.startingOn,
+			EndingOn:   period.endingOn,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartingOn.Before(records[j].StartingOn)
+	})
+	return records
+}
+
+func (maps *InMemoryMapStore) importId(ctx context.Context, actorName string, record storage.IdRecord) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	if maps.IdMap == nil {
+		maps.IdMap = map[string]uuid.UUID{}
+	}
+	if maps.history == nil {
+		maps.history = map[uuid.UUID][]storage.IdentifierRecord{}
+	}
+	if maps.actors == nil {
+		maps.actors = map[string][]uuid.UUID{}
+	}
+	uid := record.ActorId
+	key, err := spry.IdentifiersToString(record.Identifiers)
+	if err != nil {
+		return err
+	}
+	history := maps.history[uid]
+	if hasIdentifiers(history, key) {
+		return nil
+	}
+	actors := maps.actors[actorName]
+	previous, existed := maps.IdMap[key]
+
+	maps.history[uid] = append(history[:len(history):len(history)], record.IdentifierRecord)
+	maps.addEntryId(uid, key, record.Id)
+	if !contains(actors, uid) {
+		maps.actors[actorName] = append(actors[:len(actors):len(actors)], uid)
+	}
+	// current identifiers win over aliases another actor kept
+	if !record.Retired && (!existed || record.IsCurrent()) {
+		maps.IdMap[key] = uid
+	}
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.history[uid] = history
+		maps.actors[actorName] = actors
+		if existed {
+			maps.IdMap[key] = previous
+		} else {
+			delete(maps.IdMap, key)
+		}
+	})
+	return nil
+}
+
+func (maps *InMemoryMapStore) importLink(ctx context.Context, link storage.LinkRecord) error {
+	if link.IsActive() {
+		err := maps.AddLink(ctx, link.ParentType, link.ParentId, link.ChildType, link.ChildId)
+		if err != nil {
+			return err
+		}
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.started[link.ParentId][link.ChildId] = activeLink{id: link.Id, startingOn: link.StartingOn}
+		return nil
+	}
+
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	period := linkPeriod{
+		id:         link.Id,
+		parentType: link.ParentType,
+		parentId:   link.ParentId,
+		childType:  link.ChildType,
+		childId:    link.ChildId,
+		startingOn: link.StartingOn,
+		endingOn:   link.EndingOn,
+	}
+	for _, ended := range maps.ended {
+		if ended.parentId == period.parentId &&
+			ended.childId == period.childId &&
+			ended.endingOn.Equal(period.endingOn) {
+			return nil
+		}
+	}
+	maps.ended = append(maps.ended, period)
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.ended = without(maps.ended, func(ended linkPeriod) bool {
+			return ended == period
+		})
+	})
+	return nil
+}
+
+func (store *InMemoryCommandStore) forActors(actorIds []uuid.UUID) []storage.CommandRecord {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	records := []storage.CommandRecord{}
+	for _, actorId := range actorIds {
+		records = append(records, store.Commands[actorId]...)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Id.String() < records[j].Id.String()
+	})
+	return records
+}
+
+func (store *InMemoryCommandStore) importCommand(ctx context.Context, actorName string, command storage.CommandRecord) error {
+	store.lock.Lock()
+	for _, stored := range store.Commands[command.HandledBy] {
+		if stored.Id == command.Id {
+			store.lock.Unlock()
+			return nil
+		}
+	}
+	store.lock.Unlock()
+	return store.Add(ctx, actorName, command)
+}
+
+func (store *InMemoryEventStore) importEvent(ctx context.Context, event storage.EventRecord) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.Events == nil {
+		store.Events = map[uuid.UUID][]storage.EventRecord{}
+	}
+	stored := store.Events[event.ActorId]
+	for _, e := range stored {
+		if e.Id == event.Id {
+			return nil
+		}
+	}
+	// streams are kept in the order the events were created
+	events := append(stored[:len(stored):len(stored)], event)
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id.String() < events[j].Id.String()
+	})
+	store.Events[event.ActorId] = events
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		store.Events[event.ActorId] = without(store.Events[event.ActorId], func(e storage.EventRecord) bool {
+			return e.Id == event.Id
+		})
+	})
+	onCommit(ctx, func() {
+		store.commit([]storage.EventRecord{event})
+	})
+	return nil
+}
+
+func (store *InMemorySnapshotStore) ofType(actorName string) []storage.Snapshot {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	snapshots := []storage.Snapshot{}
+	for _, stored := range store.Snapshots {
+		for _, snapshot := range stored {
+			if snapshot.Type == actorName {
+				snapshots = append(snapshots, copySnapshot(snapshot))
+			}
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Id.String() < snapshots[j].Id.String()
+	})
+	return snapshots
+}
+
+func (store *InMemorySnapshotStore) importSnapshot(ctx context.Context, actorName string, snapshot storage.Snapshot) error {
+	store.lock.Lock()
+	for _, stored := range store.Snapshots[snapshot.ActorId] {
+		if stored.Id == snapshot.Id {
+			store.lock.Unlock()
+			return nil
+		}
+	}
+	store.lock.Unlock()
+	return store.Add(ctx, actorName, snapshot, false)
+}
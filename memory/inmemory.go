@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
@@ -12,6 +14,7 @@ import (
 type IdLinks map[string]map[uuid.UUID]storage.AggregatedIds
 
 type InMemoryCommandStore struct {
+	lock     sync.Mutex
 	Commands map[uuid.UUID][]storage.CommandRecord
 }
 
@@ -29,6 +32,8 @@ func (store *InMemoryCommandStore) Add(
 	ctx context.Context,
 	actorName string,
 	command storage.CommandRecord) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
 	if store.Commands == nil {
 		store.Commands = map[uuid.UUID][]storage.CommandRecord{}
 	}
@@ -38,17 +43,76 @@ func (store *InMemoryCommandStore) Add(
 	} else {
 		store.Commands[actorId] = []storage.CommandRecord{command}
 	}
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		store.Commands[actorId] = without(store.Commands[actorId], func(c storage.CommandRecord) bool {
+			return c.Id == command.Id
+		})
+	})
 	return nil
 }
 
+func (store *InMemoryCommandStore) Fetch(
+	ctx context.Context,
+	actorName string,
+	commandIds []uuid.UUID,
+	types storage.TypeMap) ([]storage.CommandRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	wanted := map[uuid.UUID]bool{}
+	for _, id := range commandIds {
+		wanted[id] = true
+	}
+	found := []storage.CommandRecord{}
+	for _, commands := range store.Commands {
+		for _, command := range commands {
+			if wanted[command.Id] {
+				found = append(found, command)
+			}
+		}
+	}
+	return found, nil
+}
+
 type InMemoryEventStore struct {
-	Events map[uuid.UUID][]storage.EventRecord
+	lock        sync.Mutex
+	Events      map[uuid.UUID][]storage.EventRecord
+	subscribers map[string][]chan struct{}
+	// the order transactions committed events in, which feeds read by
+	commits   uint64
+	positions map[uuid.UUID]uint64
 }
 
 func (store *InMemoryEventStore) Add(ctx context.Context, events []storage.EventRecord) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
 	if store.Events == nil {
 		store.Events = map[uuid.UUID][]storage.EventRecord{}
 	}
+
+	// reject the whole batch if any actor's stream moved past the
+	// version the events were created against
+	versions := map[uuid.UUID]uint64{}
+	for _, event := range events {
+		if event.Version == 0 {
+			continue
+		}
+		last, ok := versions[event.ActorId]
+		if !ok {
+			last = store.lastVersion(event.ActorId)
+		}
+		if event.Version <= last {
+			return storage.ErrConcurrencyConflict{
+				ActorName: event.ActorName,
+				ActorId:   event.ActorId,
+				Version:   event.Version,
+			}
+		}
+		versions[event.ActorId] = event.Version
+	}
+
+	added := map[uuid.UUID]bool{}
 	for _, event := range events {
 		actorId := event.ActorId
 		if stored, ok := store.Events[actorId]; ok {
@@ -56,10 +120,87 @@ func (store *InMemoryEventStore) Add(ctx context.Context, events []storage.Event
 		} else {
 			store.Events[actorId] = []storage.EventRecord{event}
 		}
+		added[event.Id] = true
 	}
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		for _, event := range events {
+			store.Events[event.ActorId] = without(store.Events[event.ActorId], func(e storage.EventRecord) bool {
+				return added[e.Id]
+			})
+		}
+	})
+	onCommit(ctx, func() {
+		store.commit(events)
+	})
 	return nil
 }
 
+// positions the events after everything committed before them and
+// wakes the subscribers to their actor types
+func (store *InMemoryEventStore) commit(events []storage.EventRecord) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.positions == nil {
+		store.positions = map[uuid.UUID]uint64{}
+	}
+	store.commits++
+	signals := map[string]bool{}
+	for _, event := range events {
+		store.positions[event.Id] = store.commits
+		signals[event.ActorName] = true
+	}
+	for actorName := range signals {
+		store.signal(actorName)
+	}
+}
+
+func (store *InMemoryEventStore) Notify(ctx context.Context, actorName string) (<-chan struct{}, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.subscribers == nil {
+		store.subscribers = map[string][]chan struct{}{}
+	}
+	signal := make(chan struct{}, 1)
+	store.subscribers[actorName] = append(store.subscribers[actorName], signal)
+
+	go func() {
+		<-ctx.Done()
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		list := store.subscribers[actorName]
+		for i, s := range list {
+			if s == signal {
+				store.subscribers[actorName] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+	}()
+	return signal, nil
+}
+
+// a pending signal already tells the subscriber to read, so
+// signals never block the writer
+func (store *InMemoryEventStore) signal(actorName string) {
+	for _, s := range store.subscribers[actorName] {
+		select {
+		case s <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (store *InMemoryEventStore) lastVersion(actorId uuid.UUID) uint64 {
+	last := uint64(0)
+	for _, e := range store.Events[actorId] {
+		if e.Version > last {
+			last = e.Version
+		}
+	}
+	return last
+}
+
 func (store *InMemoryEventStore) FetchAggregatedSince(
 	ctx context.Context,
 	actorName string,
@@ -68,10 +209,6 @@ func (store *InMemoryEventStore) FetchAggregatedSince(
 	idMap storage.LastEventMap,
 	types storage.TypeMap) ([]storage.EventRecord, error) {
 
-	if store.Events == nil {
-		store.Events = map[uuid.UUID][]storage.EventRecord{}
-	}
-
 	var records []storage.EventRecord
 	own, err := store.FetchSince(ctx, actorName, actorId, eventUUID, types)
 	if err != nil {
@@ -85,7 +222,11 @@ func (store *InMemoryEventStore) FetchAggregatedSince(
 			if err != nil {
 				return nil, err
 			}
-			records = append(records, list...)
+			for _, record := range list {
+				if idMap.IsLinkedOn(childName, id, record.CreatedOn) {
+					records = append(records, record)
+				}
+			}
 		}
 	}
 
@@ -96,12 +237,52 @@ func (store *InMemoryEventStore) FetchAggregatedSince(
 	return records, nil
 }
 
+func (store *InMemoryEventStore) FetchAllSince(
+	ctx context.Context,
+	actorName string,
+	eventUUID uuid.UUID,
+	limit int,
+	types storage.TypeMap) ([]storage.EventRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	// an unknown checkpoint reads from the start
+	after := storage.EventPosition{}
+	if tx, ok := store.positions[eventUUID]; ok {
+		after = storage.EventPosition{Tx: tx, Id: eventUUID}
+	}
+	records := []storage.EventRecord{}
+	for _, stored := range store.Events {
+		for _, e := range stored {
+			tx, committed := store.positions[e.Id]
+			if !committed || e.ActorName != actorName {
+				continue
+			}
+			e.Position = storage.EventPosition{Tx: tx, Id: e.Id}
+			if after.Before(e.Position) {
+				records = append(records, e)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Position.Before(records[j].Position)
+	})
+
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
 func (store *InMemoryEventStore) FetchSince(
 	ctx context.Context,
 	actorName string,
 	actorId uuid.UUID,
 	eventUUID uuid.UUID,
 	types storage.TypeMap) ([]storage.EventRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
 	if store.Events == nil {
 		store.Events = map[uuid.UUID][]storage.EventRecord{}
 	}
@@ -112,21 +293,247 @@ func (store *InMemoryEventStore) FetchSince(
 	}
 }
 
+// when an active link started and the id it's archived with
+type activeLink struct {
+	id         uuid.UUID
+	startingOn time.Time
+}
+
+// a link between a parent and a child that has ended
+type linkPeriod struct {
+	id         uuid.UUID
+	parentType string
+	parentId   uuid.UUID
+	childType  string
+	childId    uuid.UUID
+	startingOn time.Time
+	endingOn   time.Time
+}
+
 type InMemoryMapStore struct {
+	lock    sync.Mutex
 	IdMap   map[string]uuid.UUID
 	LinkMap IdLinks
+	// every set of identifiers each actor has been known by
+	history map[uuid.UUID][]storage.IdentifierRecord
+	// the id each history entry is archived with, by actor and identifiers
+	entryIds map[uuid.UUID]map[string]uuid.UUID
+	// when each active link started, keyed the same as LinkMap
+	started map[uuid.UUID]map[uuid.UUID]activeLink
+	ended   []linkPeriod
+	// the ids of each type of actor, in the order they were added
+	actors map[string][]uuid.UUID
 }
 
 func (maps *InMemoryMapStore) AddId(ctx context.Context, actorName string, ids spry.Identifiers, uid uuid.UUID) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	if maps.IdMap == nil {
+		maps.IdMap = map[string]uuid.UUID{}
+	}
+	key, _ := spry.IdentifiersToString(ids)
+	previous, existed := maps.IdMap[key]
+	maps.IdMap[key] = uid
+	actors := maps.actors[actorName]
+	if !contains(actors, uid) {
+		if maps.actors == nil {
+			maps.actors = map[string][]uuid.UUID{}
+		}
+		maps.actors[actorName] = append(actors[:len(actors):len(actors)], uid)
+	}
+	history := maps.history[uid]
+	if !hasIdentifiers(history, key) {
+		if maps.history == nil {
+			maps.history = map[uuid.UUID][]storage.IdentifierRecord{}
+		}
+		maps.history[uid] = append(history[:len(history):len(history)], storage.IdentifierRecord{
+			Identifiers: ids,
+			StartingOn:  time.Now().UTC(),
+		})
+		maps.addEntryId(uid, key, uuid.Nil)
+	}
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.history[uid] = history
+		if maps.actors != nil {
+			maps.actors[actorName] = actors
+		}
+		if existed {
+			maps.IdMap[key] = previous
+		} else {
+			delete(maps.IdMap, key)
+		}
+	})
+	return nil
+}
+
+func contains(ids []uuid.UUID, uid uuid.UUID) bool {
+	for _, id := range ids {
+		if id == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func (maps *InMemoryMapStore) GetActorIds(ctx context.Context, actorName string) ([]uuid.UUID, error) {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	ids := make([]uuid.UUID, len(maps.actors[actorName]))
+	copy(ids, maps.actors[actorName])
+	return ids, nil
+}
+
+// keeps the id an entry was first given so exports are repeatable;
+// without an id one is generated
+func (maps *InMemoryMapStore) addEntryId(uid uuid.UUID, key string, id uuid.UUID) {
+	if maps.entryIds == nil {
+		maps.entryIds = map[uuid.UUID]map[string]uuid.UUID{}
+	}
+	if maps.entryIds[uid] == nil {
+		maps.entryIds[uid] = map[string]uuid.UUID{}
+	}
+	if _, ok := maps.entryIds[uid][key]; ok {
+		return
+	}
+	if id == uuid.Nil {
+		id, _ = storage.GetId()
+	}
+	maps.entryIds[uid][key] = id
+}
+
+func hasIdentifiers(history []storage.IdentifierRecord, key string) bool {
+	for _, record := range history {
+		if k, _ := spry.IdentifiersToString(record.Identifiers); k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// aliases another actor kept can be taken over, its current
+// identifiers can't
+func holdsIdentifiers(history []storage.IdentifierRecord, key string) bool {
+	for _, record := range history {
+		if k, _ := spry.IdentifiersToString(record.Identifiers); k == key {
+			return record.IsCurrent() && !record.Retired
+		}
+	}
+	return false
+}
+
+func (maps *InMemoryMapStore) Rekey(
+	ctx context.Context,
+	actorName string,
+	uid uuid.UUID,
+	ids spry.Identifiers,
+	retire bool,
+	on time.Time) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
 	if maps.IdMap == nil {
 		maps.IdMap = map[string]uuid.UUID{}
 	}
+	if maps.history == nil {
+		maps.history = map[uuid.UUID][]storage.IdentifierRecord{}
+	}
 	key, _ := spry.IdentifiersToString(ids)
+	if holder, ok := maps.IdMap[key]; ok && holder != uid && holdsIdentifiers(maps.history[holder], key) {
+		return storage.ErrIdentifiersInUse{ActorName: actorName, Identifiers: ids}
+	}
+
+	// the id map entries to put back on rollback
+	previous := map[string]uuid.UUID{}
+	history := maps.history[uid]
+	records := append([]storage.IdentifierRecord{}, history...)
+
+	found := false
+	for i, record := range records {
+		k, _ := spry.IdentifiersToString(record.Identifiers)
+		if k == key {
+			records[i].StartingOn = on
+			records[i].EndingOn = time.Time{}
+			records[i].Retired = false
+			found = true
+		} else if record.IsCurrent() {
+			records[i].EndingOn = on
+			records[i].Retired = retire
+			if retire && maps.IdMap[k] == uid {
+				previous[k] = uid
+				delete(maps.IdMap, k)
+			}
+		}
+	}
+	if !found {
+		records = append(records, storage.IdentifierRecord{
+			Identifiers: ids,
+			StartingOn:  on,
+		})
+		maps.addEntryId(uid, key, uuid.Nil)
+	}
+	previous[key] = maps.IdMap[key]
 	maps.IdMap[key] = uid
+	maps.history[uid] = records
+
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.history[uid] = history
+		for k, id := range previous {
+			if id == uuid.Nil {
+				delete(maps.IdMap, k)
+			} else {
+				maps.IdMap[k] = id
+			}
+		}
+	})
+	return nil
+}
+
+func (maps *InMemoryMapStore) RetireIds(ctx context.Context, actorName string, uid uuid.UUID, on time.Time) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	history := maps.history[uid]
+	records := append([]storage.IdentifierRecord{}, history...)
+	retired := []string{}
+	for i, record := range records {
+		if record.IsCurrent() {
+			records[i].EndingOn = on
+		}
+		records[i].Retired = true
+		key, _ := spry.IdentifiersToString(record.Identifiers)
+		if maps.IdMap[key] == uid {
+			retired = append(retired, key)
+			delete(maps.IdMap, key)
+		}
+	}
+	if maps.history != nil {
+		maps.history[uid] = records
+	}
+
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		if maps.history != nil {
+			maps.history[uid] = history
+		}
+		for _, key := range retired {
+			maps.IdMap[key] = uid
+		}
+	})
 	return nil
 }
 
+func (maps *InMemoryMapStore) GetIdHistory(ctx context.Context, actorName string, uid uuid.UUID) ([]storage.IdentifierRecord, error) {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	return append([]storage.IdentifierRecord{}, maps.history[uid]...), nil
+}
+
 func (maps *InMemoryMapStore) AddLink(ctx context.Context, parentType string, parentId uuid.UUID, childType string, childId uuid.UUID) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
 	if maps.LinkMap == nil {
 		maps.LinkMap = IdLinks{}
 	}
@@ -136,15 +543,85 @@ func (maps *InMemoryMapStore) AddLink(ctx context.Context, parentType string, pa
 	if maps.LinkMap[parentType][parentId] == nil {
 		maps.LinkMap[parentType][parentId] = storage.AggregatedIds{}
 	}
-	if maps.LinkMap[parentType][parentId][childType] == nil {
-		maps.LinkMap[parentType][parentId][childType] = []uuid.UUID{childId}
-	} else {
-		maps.LinkMap[parentType][parentId][childType] = append(maps.LinkMap[parentType][parentId][childType], childId)
+	// a child only has one active link to a parent at a time
+	for _, id := range maps.LinkMap[parentType][parentId][childType] {
+		if id == childId {
+			return nil
+		}
 	}
+	maps.LinkMap[parentType][parentId][childType] = append(maps.LinkMap[parentType][parentId][childType], childId)
+	if maps.started == nil {
+		maps.started = map[uuid.UUID]map[uuid.UUID]activeLink{}
+	}
+	if maps.started[parentId] == nil {
+		maps.started[parentId] = map[uuid.UUID]activeLink{}
+	}
+	id, _ := storage.GetId()
+	maps.started[parentId][childId] = activeLink{id: id, startingOn: time.Now().UTC()}
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		delete(maps.started[parentId], childId)
+		children := maps.LinkMap[parentType][parentId][childType]
+		for i := len(children) - 1; i >= 0; i-- {
+			if children[i] == childId {
+				maps.LinkMap[parentType][parentId][childType] = append(children[:i:i], children[i+1:]...)
+				break
+			}
+		}
+	})
+	return nil
+}
+
+func (maps *InMemoryMapStore) RemoveLink(
+	ctx context.Context,
+	parentType string,
+	parentId uuid.UUID,
+	childType string,
+	childId uuid.UUID,
+	endingOn time.Time) error {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
+	children := maps.LinkMap[parentType][parentId][childType]
+	index := -1
+	for i, id := range children {
+		if id == childId {
+			index = i
+			break
+		}
+	}
+	// there's nothing to end if the child isn't linked
+	if index < 0 {
+		return nil
+	}
+	maps.LinkMap[parentType][parentId][childType] = append(children[:index:index], children[index+1:]...)
+	started := maps.started[parentId][childId]
+	delete(maps.started[parentId], childId)
+	maps.ended = append(maps.ended, linkPeriod{
+		id:         started.id,
+		parentType: parentType,
+		parentId:   parentId,
+		childType:  childType,
+		childId:    childId,
+		startingOn: started.startingOn,
+		endingOn:   endingOn,
+	})
+	onRollback(ctx, func() {
+		maps.lock.Lock()
+		defer maps.lock.Unlock()
+		maps.ended = maps.ended[:len(maps.ended)-1]
+		maps.started[parentId][childId] = started
+		maps.LinkMap[parentType][parentId][childType] = append(
+			maps.LinkMap[parentType][parentId][childType],
+			childId,
+		)
+	})
 	return nil
 }
 
 func (maps *InMemoryMapStore) GetId(ctx context.Context, actorName string, ids spry.Identifiers) (uuid.UUID, error) {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
 	if maps.IdMap == nil {
 		maps.IdMap = map[string]uuid.UUID{}
 	}
@@ -160,6 +637,8 @@ func (maps *InMemoryMapStore) GetIdMap(
 	ctx context.Context,
 	actorName string,
 	uid uuid.UUID) (storage.AggregateIdMap, error) {
+	maps.lock.Lock()
+	defer maps.lock.Unlock()
 	if maps.IdMap == nil {
 		maps.IdMap = map[string]uuid.UUID{}
 	}
@@ -175,6 +654,14 @@ func (maps *InMemoryMapStore) GetIdMap(
 	if actors, ok := aggregates[uid]; ok {
 		for k, v := range actors {
 			idMap.AddIdsFor(k, v...)
+			for _, childId := range v {
+				idMap.AddLinkPeriod(k, childId, maps.started[uid][childId].startingOn, time.Time{})
+			}
+		}
+	}
+	for _, link := range maps.ended {
+		if link.parentType == actorName && link.parentId == uid {
+			idMap.AddLinkPeriod(link.childType, link.childId, link.startingOn, link.endingOn)
 		}
 	}
 
@@ -182,56 +669,139 @@ func (maps *InMemoryMapStore) GetIdMap(
 }
 
 type InMemorySnapshotStore struct {
+	lock      sync.Mutex
 	Snapshots map[uuid.UUID][]storage.Snapshot
+	// snapshots that were superseded, kept for reference
+	Superseded map[uuid.UUID][]storage.Snapshot
 }
 
 func (store *InMemorySnapshotStore) Add(ctx context.Context, actorName string, snapshot storage.Snapshot, allowPartition bool) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
 	if store.Snapshots == nil {
 		store.Snapshots = map[uuid.UUID][]storage.Snapshot{}
 	}
 	actorId := snapshot.ActorId
 	if stored, ok := store.Snapshots[actorId]; ok {
-		store.Snapshots[actorId] = append(stored, snapshot)
+		store.Snapshots[actorId] = append(stored, copySnapshot(snapshot))
 	} else {
-		store.Snapshots[actorId] = []storage.Snapshot{snapshot}
+		store.Snapshots[actorId] = []storage.Snapshot{copySnapshot(snapshot)}
 	}
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		store.Snapshots[actorId] = without(store.Snapshots[actorId], func(s storage.Snapshot) bool {
+			return s.Id == snapshot.Id
+		})
+		if len(store.Snapshots[actorId]) == 0 {
+			delete(store.Snapshots, actorId)
+		}
+	})
+	return nil
+}
+
+func (store *InMemorySnapshotStore) Supersede(ctx context.Context, actorName string, actorId uuid.UUID, on time.Time) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	stored, ok := store.Snapshots[actorId]
+	if !ok {
+		return nil
+	}
+	if store.Superseded == nil {
+		store.Superseded = map[uuid.UUID][]storage.Snapshot{}
+	}
+	archived := store.Superseded[actorId]
+	store.Superseded[actorId] = append(archived[:len(archived):len(archived)], stored...)
+	delete(store.Snapshots, actorId)
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		// snapshots added since are newer than the ones restored
+		store.Snapshots[actorId] = append(stored[:len(stored):len(stored)], store.Snapshots[actorId]...)
+		store.Superseded[actorId] = archived
+	})
 	return nil
 }
 
 func (store *InMemorySnapshotStore) Fetch(ctx context.Context, actorName string, actorId uuid.UUID) (storage.Snapshot, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
 	if store.Snapshots == nil {
 		store.Snapshots = map[uuid.UUID][]storage.Snapshot{}
 	}
 	if stored, ok := store.Snapshots[actorId]; ok {
-		return stored[len(stored)-1], nil
+		return copySnapshot(stored[len(stored)-1]), nil
 	}
 	return storage.Snapshot{}, nil
 }
 
-type InMemoryTxProvider struct {
+func (store *InMemorySnapshotStore) FetchAsOf(ctx context.Context, actorName string, actorId uuid.UUID, asOf time.Time) (storage.Snapshot, error) {
+	return store.fetchNewest(actorId, func(snapshot storage.Snapshot) bool {
+		return !snapshot.LastEventOn.After(asOf)
+	}), nil
 }
 
-func (provider InMemoryTxProvider) Commit(ctx context.Context) error {
-	return nil
+func (store *InMemorySnapshotStore) FetchAtVersion(ctx context.Context, actorName string, actorId uuid.UUID, version uint64) (storage.Snapshot, error) {
+	return store.fetchNewest(actorId, func(snapshot storage.Snapshot) bool {
+		return snapshot.Version <= version
+	}), nil
 }
 
-func (provider InMemoryTxProvider) GetTransaction(ctx context.Context) (storage.NoOpTx, error) {
-	return storage.NoOpTx{}, nil
+func (store *InMemorySnapshotStore) fetchNewest(actorId uuid.UUID, match func(storage.Snapshot) bool) storage.Snapshot {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	stored := store.Snapshots[actorId]
+	for i := len(stored) - 1; i >= 0; i-- {
+		if match(stored[i]) {
+			return copySnapshot(stored[i])
+		}
+	}
+	return storage.Snapshot{}
 }
 
-func (provider InMemoryTxProvider) Rollback(ctx context.Context) error {
-	return nil
+// snapshots carry maps that repositories update while reading, so the
+// store keeps its own copies to avoid sharing them with callers
+func copySnapshot(snapshot storage.Snapshot) storage.Snapshot {
+	lastEvents := storage.CreateLastEvents()
+	for child, m := range snapshot.LastEvents {
+		for id, last := range m {
+			lastEvents.AddLastEventFor(child, id, last)
+		}
+	}
+	for child, m := range snapshot.LastVersions {
+		for id, version := range m {
+			lastEvents.AddLastVersionFor(child, id, version)
+		}
+	}
+	checkpoints := map[string]uuid.UUID{}
+	for actorName, last := range snapshot.Checkpoints {
+		checkpoints[actorName] = last
+	}
+	snapshot.LastEventMap = lastEvents
+	snapshot.Checkpoints = checkpoints
+	return snapshot
 }
 
 func InMemoryStorage() storage.Storage {
-	return storage.NewStorage[storage.NoOpTx](
-		&InMemoryCommandStore{},
-		&InMemoryEventStore{},
-		&InMemoryMapStore{
-			IdMap:   map[string]uuid.UUID{},
-			LinkMap: IdLinks{},
-		},
-		&InMemorySnapshotStore{},
+	commands := &InMemoryCommandStore{}
+	events := &InMemoryEventStore{}
+	maps := &InMemoryMapStore{
+		IdMap:   map[string]uuid.UUID{},
+		LinkMap: IdLinks{},
+	}
+	snapshots := &InMemorySnapshotStore{}
+	stores := storage.NewStorage[*InMemoryTx](
+		commands,
+		events,
+		maps,
+		snapshots,
 		&InMemoryTxProvider{},
-	)
+	).(storage.Stores[*InMemoryTx])
+	stores.Archive = &InMemoryArchiveStore{
+		Commands:  commands,
+		Events:    events,
+		Maps:      maps,
+		Snapshots: snapshots,
+	}
+	return stores
 }
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// keys are kept per actor type, like the Postgres keys tables
+type InMemoryKeyStore struct {
+	lock sync.Mutex
+	Keys map[string]map[uuid.UUID][]byte
+}
+
+func (store *InMemoryKeyStore) Add(ctx context.Context, actorName string, actorId uuid.UUID, key []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.Keys == nil {
+		store.Keys = map[string]map[uuid.UUID][]byte{}
+	}
+	keys, ok := store.Keys[actorName]
+	if !ok {
+		keys = map[uuid.UUID][]byte{}
+		store.Keys[actorName] = keys
+	}
+	// the first key added for an actor wins
+	if _, ok := keys[actorId]; ok {
+		return nil
+	}
+	keys[actorId] = key
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		delete(keys, actorId)
+	})
+	return nil
+}
+
+func (store *InMemoryKeyStore) Delete(ctx context.Context, actorName string, actorId uuid.UUID) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	keys := store.Keys[actorName]
+	key, ok := keys[actorId]
+	if !ok {
+		return nil
+	}
+	delete(keys, actorId)
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		keys[actorId] = key
+	})
+	return nil
+}
+
+func (store *InMemoryKeyStore) Fetch(ctx context.Context, actorName string, actorId uuid.UUID) ([]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.Keys[actorName][actorId], nil
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry/storage"
+)
+
+type InMemoryOutboxStore struct {
+	lock    sync.Mutex
+	Records map[string]map[uuid.UUID]*storage.OutboxRecord
+}
+
+func (store *InMemoryOutboxStore) Add(ctx context.Context, events []storage.EventRecord) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.Records == nil {
+		store.Records = map[string]map[uuid.UUID]*storage.OutboxRecord{}
+	}
+	for _, event := range events {
+		if store.Records[event.ActorName] == nil {
+			store.Records[event.ActorName] = map[uuid.UUID]*storage.OutboxRecord{}
+		}
+		if _, ok := store.Records[event.ActorName][event.Id]; !ok {
+			store.Records[event.ActorName][event.Id] = &storage.OutboxRecord{
+				Event:         event,
+				NextAttemptOn: event.CreatedOn,
+			}
+			actorName, eventId := event.ActorName, event.Id
+			onRollback(ctx, func() {
+				store.lock.Lock()
+				defer store.lock.Unlock()
+				delete(store.Records[actorName], eventId)
+			})
+		}
+	}
+	return nil
+}
+
+func (store *InMemoryOutboxStore) FetchPending(
+	ctx context.Context,
+	actorName string,
+	limit int,
+	types storage.TypeMap) ([]storage.OutboxRecord, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := time.Now()
+	pending := []storage.OutboxRecord{}
+	for _, record := range store.Records[actorName] {
+		if !record.NextAttemptOn.After(now) {
+			pending = append(pending, *record)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Event.Id.String() < pending[j].Event.Id.String()
+	})
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (store *InMemoryOutboxStore) MarkPublished(ctx context.Context, actorName string, eventId uuid.UUID) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if record, ok := store.Records[actorName][eventId]; ok {
+		delete(store.Records[actorName], eventId)
+		onRollback(ctx, func() {
+			store.lock.Lock()
+			defer store.lock.Unlock()
+			store.Records[actorName][eventId] = record
+		})
+	}
+	return nil
+}
+
+func (store *InMemoryOutboxStore) MarkUnpublished(ctx context.Context, actorName string, eventId uuid.UUID, nextAttempt time.Time) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if record, ok := store.Records[actorName][eventId]; ok {
+		store.restoreOnRollback(ctx, record)
+		record.Attempts++
+		record.NextAttemptOn = nextAttempt
+	}
+	return nil
+}
+
+func (store *InMemoryOutboxStore) restoreOnRollback(ctx context.Context, record *storage.OutboxRecord) {
+	previous := *record
+	onRollback(ctx, func() {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+		*record = previous
+	})
+}
+
+// collects published events so tests can assert on them
+type InMemoryPublisher struct {
+	lock      sync.Mutex
+	Published []storage.EventRecord
+	// when set, Publish returns the error instead of collecting the event
+	Fail error
+}
+
+func (publisher *InMemoryPublisher) Publish(ctx context.Context, record storage.EventRecord) error {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	if publisher.Fail != nil {
+		return publisher.Fail
+	}
+	publisher.Published = append(publisher.Published, record)
+	return nil
+}
+
+func (publisher *InMemoryPublisher) GetPublished() []storage.EventRecord {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	return append([]storage.EventRecord{}, publisher.Published...)
+}
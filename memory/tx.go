@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/legitbiz/spry/storage"
+)
+
+// in-memory writes go straight to the stores, so a transaction keeps
+// the steps that undo them in case it is rolled back. Other readers
+// can see writes before they are committed, so steps that announce
+// writes wait for the commit.
+type InMemoryTx struct {
+	lock      sync.Mutex
+	undo      []func()
+	committed []func()
+}
+
+func (tx *InMemoryTx) Commit() error {
+	tx.lock.Lock()
+	committed := tx.committed
+	tx.undo = nil
+	tx.committed = nil
+	tx.lock.Unlock()
+	for _, step := range committed {
+		step()
+	}
+	return nil
+}
+
+func (tx *InMemoryTx) Rollback() error {
+	tx.lock.Lock()
+	undo := tx.undo
+	tx.undo = nil
+	tx.committed = nil
+	tx.lock.Unlock()
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i]()
+	}
+	return nil
+}
+
+func (tx *InMemoryTx) onRollback(step func()) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+	tx.undo = append(tx.undo, step)
+}
+
+// stores can be called without a transaction, in which case
+// their writes can't be undone
+func onRollback(ctx context.Context, step func()) {
+	if tx, ok := storage.LookupTx[*InMemoryTx](ctx); ok && tx != nil {
+		tx.onRollback(step)
+	}
+}
+
+func (tx *InMemoryTx) onCommit(step func()) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+	tx.committed = append(tx.committed, step)
+}
+
+// without a transaction the write is already final
+func onCommit(ctx context.Context, step func()) {
+	if tx, ok := storage.LookupTx[*InMemoryTx](ctx); ok && tx != nil {
+		tx.onCommit(step)
+	} else {
+		step()
+	}
+}
+
+func without[T any](list []T, remove func(T) bool) []T {
+	kept := make([]T, 0, len(list))
+	for _, item := range list {
+		if !remove(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+type InMemoryTxProvider struct {
+}
+
+func (provider InMemoryTxProvider) Commit(ctx context.Context) error {
+	return storage.GetTx[*InMemoryTx](ctx).Commit()
+}
+
+func (provider InMemoryTxProvider) GetTransaction(ctx context.Context) (*InMemoryTx, error) {
+	return &InMemoryTx{}, nil
+}
+
+func (provider InMemoryTxProvider) Rollback(ctx context.Context) error {
+	return storage.GetTx[*InMemoryTx](ctx).Rollback()
+}
@@ -1,5 +1,30 @@
 package spry
 
+import "time"
+
+// how HandleAll treats a command that is rejected part way through a batch,
+// and how a process manager treats a command it issued being rejected
+type BatchPolicy int
+
+const (
+	// nothing in the batch is stored except the rejected command,
+	// which is logged on its own
+	AbortBatch BatchPolicy = iota
+	// the rejected command is logged and the rest of the batch is handled
+	SkipRejected
+)
+
+// what happens to an actor's identifiers when its state changes them
+type IdentifierPolicy int
+
+const (
+	// the previous identifiers keep resolving to the actor
+	KeepAliases IdentifierPolicy = iota
+	// the previous identifiers stop resolving and can be used
+	// by another actor
+	RetireIdentifiers
+)
+
 type ActorMeta struct {
 	// how many events should occur before the next snapshot
 	SnapshotFrequency int
@@ -11,6 +36,44 @@ type ActorMeta struct {
 	// requires a storage adapter for a database that can
 	// detect this
 	SnapshotDuringPartition bool
+	// how many times a command is attempted when another writer
+	// appended to the actor's stream after it was loaded
+	// (0 or 1 disables retries)
+	RetryAttempts int
+	// how long to wait before the first retry, doubled for each
+	// retry after it up to MaxBackoff
+	RetryBackoff time.Duration
+	// the longest a retry waits before jitter is added, the default
+	// when it isn't set
+	MaxBackoff time.Duration
+	// the upper bound of a random delay added to each backoff
+	RetryJitter time.Duration
+	// when set, identifies the shape of the actor's state instead of
+	// a fingerprint of its fields; change it to invalidate snapshots
+	SchemaVersion int
+	// what HandleAll, or a process manager, does when one of its
+	// commands is rejected
+	BatchPolicy BatchPolicy
+	// what happens to previous identifiers when the actor is re-keyed
+	IdentifierPolicy IdentifierPolicy
+}
+
+// how many events are applied between snapshots, the default
+// when SnapshotFrequency isn't set
+func (meta ActorMeta) GetSnapshotFrequency() int {
+	if meta.SnapshotFrequency <= 0 {
+		return default_meta.SnapshotFrequency
+	}
+	return meta.SnapshotFrequency
+}
+
+// the longest a retry waits before jitter, the default when
+// MaxBackoff isn't set
+func (meta ActorMeta) GetMaxBackoff() time.Duration {
+	if meta.MaxBackoff <= 0 {
+		return default_meta.MaxBackoff
+	}
+	return meta.MaxBackoff
 }
 
 type HasMeta interface {
@@ -22,6 +85,7 @@ var default_meta = ActorMeta{
 	SnapshotDuringRead:      false,
 	SnapshotDuringWrite:     true,
 	SnapshotDuringPartition: true,
+	MaxBackoff:              time.Second,
 }
 
 func GetActorMeta[T any]() ActorMeta {
@@ -36,6 +100,9 @@ func GetActorMeta[T any]() ActorMeta {
 type EventMetadata struct {
 	CreatedBy  string
 	CreatedFor string
+	// the child actor type this event removes from the aggregate; the
+	// child's identifiers come from the event's identifier set
+	Unlinks string
 }
 
 func (e EventMetadata) GetEventMeta() EventMetadata {
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/storage"
+)
+
+type PostgresArchiveStore struct {
+	Pool      *pgxpool.Pool
+	Templates storage.StringTemplate
+}
+
+func (store *PostgresArchiveStore) Export(ctx context.Context, actorName string, write func(storage.ArchiveRecord) error) error {
+	tx := storage.GetTx[pgx.Tx](ctx)
+	err := store.exportIds(ctx, tx, actorName, write)
+	if err != nil {
+		return err
+	}
+	err = store.exportLinks(ctx, tx, actorName, write)
+	if err != nil {
+		return err
+	}
+	// commands, events and snapshots are stored as the JSON of their records
+	for _, kind := range []string{storage.ArchivedCommand, storage.ArchivedEvent, storage.ArchivedSnapshot} {
+		err = store.exportContent(ctx, tx, actorName, kind, write)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *PostgresArchiveStore) exportIds(ctx context.Context, tx pgx.Tx, actorName string, write func(storage.ArchiveRecord) error) error {
+	query, _ := store.Templates.Execute("select_archived_ids.sql", queryData(actorName))
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		var endingOn *time.Time
+		record := storage.IdRecord{}
+		err = rows.Scan(&record.Id, &data, &record.ActorId, &record.StartingOn, &endingOn, &record.Retired)
+		if err != nil {
+			return err
+		}
+		record.Identifiers, err = spry.FromJson[spry.Identifiers](data)
+		if err != nil {
+			return err
+		}
+		if endingOn != nil {
+			record.EndingOn = *endingOn
+		}
+		err = writeRecord(storage.ArchivedId, actorName, record, write)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (store *PostgresArchiveStore) exportLinks(ctx context.Context, tx pgx.Tx, actorName string, write func(storage.ArchiveRecord) error) error {
+	query, _ := store.Templates.Execute("select_archived_links.sql", queryData(actorName))
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var endingOn *time.Time
+		record := storage.LinkRecord{}
+		err = rows.Scan(
+			&record.Id,
+			&record.ParentType,
+			&record.ParentId,
+			&record.ChildType,
+			&record.ChildId,
+			&record.StartingOn,
+			&endingOn,
+		)
+		if err != nil {
+			return err
+		}
+		if endingOn != nil {
+			record.EndingOn = *endingOn
+		}
+		err = writeRecord(storage.ArchivedLink, actorName, record, write)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (store *PostgresArchiveStore) exportContent(ctx context.Context, tx pgx.Tx, actorName string, kind string, write func(storage.ArchiveRecord) error) error {
+	template := fmt.Sprintf("select_archived_%ss.sql", kind)
+	query, _ := store.Templates.Execute(template, queryData(actorName))
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var content []byte
+		err = rows.Scan(&content)
+		if err != nil {
+			return err
+		}
+		err = write(storage.ArchiveRecord{Kind: kind, ActorName: actorName, Record: content})
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func writeRecord(kind string, actorName string, record any, write func(storage.ArchiveRecord) error) error {
+	archived, err := storage.NewArchiveRecord(kind, actorName, record)
+	if err != nil {
+		return err
+	}
+	return write(archived)
+}
+
+// records are stored as they were exported, so personal data stays
+// encrypted and event types don't need to be registered
+func (store *PostgresArchiveStore) Import(ctx context.Context, record storage.ArchiveRecord, types storage.TypeMap) error {
+	tx := storage.GetTx[pgx.Tx](ctx)
+	switch record.Kind {
+	case storage.ArchivedId:
+		id, err := spry.FromJson[storage.IdRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		data, err := spry.ToJson(id.Identifiers)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, tx, "insert_archived_id.sql", record.ActorName,
+			id.Id, data, id.ActorId, id.StartingOn, nullTime(id.EndingOn), id.Retired)
+	case storage.ArchivedLink:
+		link, err := spry.FromJson[storage.LinkRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, tx, "insert_archived_link.sql", record.ActorName,
+			link.Id, link.ParentType, link.ParentId, link.ChildType, link.ChildId,
+			link.IsActive(), link.StartingOn, nullTime(link.EndingOn))
+	case storage.ArchivedCommand:
+		command, err := spry.FromJson[storage.CommandRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, tx, "insert_archived_command.sql", record.ActorName,
+			command.Id, command.HandledBy, []byte(record.Record), command.CreatedOn, command.HandledVersion)
+	case storage.ArchivedEvent:
+		event, err := spry.FromJson[storage.EventRecord](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, tx, "insert_archived_event.sql", record.ActorName,
+			event.Id, event.ActorId, []byte(record.Record), event.CreatedOn, event.Version)
+	case storage.ArchivedSnapshot:
+		snapshot, err := spry.FromJson[storage.Snapshot](record.Record)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, tx, "insert_archived_snapshot.sql", record.ActorName,
+			snapshot.Id, snapshot.ActorId, []byte(record.Record),
+			snapshot.LastCommandId, snapshot.LastCommandOn,
+			snapshot.LastEventId, snapshot.LastEventOn, snapshot.Version)
+	}
+	return fmt.Errorf("%s is not a kind of archived record", record.Kind)
+}
+
+func (store *PostgresArchiveStore) exec(ctx context.Context, tx pgx.Tx, template string, actorName string, args ...any) error {
+	query, _ := store.Templates.Execute(template, queryData(actorName))
+	_, err := tx.Exec(ctx, query, args...)
+	return err
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
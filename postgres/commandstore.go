@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 
+	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/legitbiz/spry"
@@ -43,3 +44,42 @@ func (store *PostgresCommandStore) Add(ctx context.Context, actorName string, co
 	)
 	return err
 }
+
+func (store *PostgresCommandStore) Fetch(
+	ctx context.Context,
+	actorName string,
+	commandIds []uuid.UUID,
+	types storage.TypeMap) ([]storage.CommandRecord, error) {
+	query, _ := store.Templates.Execute(
+		"select_commands.sql",
+		queryData(actorName),
+	)
+	ids := make([]string, len(commandIds))
+	for i, id := range commandIds {
+		ids[i] = id.String()
+	}
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []storage.CommandRecord{}
+	for rows.Next() {
+		buffer := []byte{}
+		err := rows.Scan(&buffer)
+		if err != nil {
+			return nil, err
+		}
+		record, err := spry.FromJson[storage.CommandRecord](buffer)
+		if err != nil {
+			return nil, err
+		}
+		record, err = types.DecodeCommand(record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -2,9 +2,13 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/legitbiz/spry"
@@ -19,6 +23,7 @@ type PostgresEventStore struct {
 func (store *PostgresEventStore) Add(ctx context.Context, events []storage.EventRecord) error {
 	tx := storage.GetTx[pgx.Tx](ctx)
 	batch := pgx.Batch{}
+	notify := map[string]bool{}
 	for _, event := range events {
 		data, err := spry.ToJson(event)
 		if err != nil {
@@ -34,16 +39,78 @@ func (store *PostgresEventStore) Add(ctx context.Context, events []storage.Event
 			event.ActorId,
 			data,
 			event.CreatedOn,
-			event.CreatedByVersion,
+			event.Version,
 		)
+		notify[event.ActorName] = true
+	}
+	// notifications are only delivered once the transaction commits
+	for actorName := range notify {
+		batch.Queue("SELECT pg_notify($1, '')", channelFor(actorName))
 	}
 
 	results := tx.SendBatch(ctx, &batch)
-	_, _ = results.Exec()
-	err := results.Close()
+	for _, event := range events {
+		if _, err := results.Exec(); err != nil {
+			_ = results.Close()
+			return asConflict(err, event)
+		}
+	}
+	return results.Close()
+}
+
+const uniqueViolation = "23505"
+
+// a unique violation on (actor_id, version) means another writer
+// appended to the actor's stream since the baseline was loaded
+func asConflict(err error, event storage.EventRecord) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return storage.ErrConcurrencyConflict{
+			ActorName: event.ActorName,
+			ActorId:   event.ActorId,
+			Version:   event.Version,
+		}
+	}
 	return err
 }
 
+func channelFor(actorName string) string {
+	return strings.ToLower(actorName) + "_events"
+}
+
+// listens on a dedicated connection for the notifications Add sends
+// when events for the actor type are committed
+func (store *PostgresEventStore) Notify(ctx context.Context, actorName string) (<-chan struct{}, error) {
+	conn, err := store.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channel := pgx.Identifier{channelFor(actorName)}.Sanitize()
+	_, err = conn.Exec(ctx, "LISTEN "+channel)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	signal := make(chan struct{}, 1)
+	go func() {
+		// the connection can't be returned to the pool while listening
+		defer conn.Release()
+		defer func() { _, _ = conn.Exec(context.Background(), "UNLISTEN "+channel) }()
+		for {
+			_, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case signal <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return signal, nil
+}
+
 func (store *PostgresEventStore) FetchAggregatedSince(
 	ctx context.Context,
 	actorName string,
@@ -61,7 +128,12 @@ func (store *PostgresEventStore) FetchAggregatedSince(
 
 	for childName, childMap := range idMap.LastEvents {
 		for id, last := range childMap {
-			list, err := store.FetchSince(ctx, childName, id, last, types)
+			var list []storage.EventRecord
+			if periods := idMap.GetLinkPeriodsFor(childName, id); len(periods) > 0 {
+				list, err = store.fetchWithin(ctx, childName, id, last, periods, types)
+			} else {
+				list, err = store.FetchSince(ctx, childName, id, last, types)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -76,6 +148,49 @@ func (store *PostgresEventStore) FetchAggregatedSince(
 	return records, nil
 }
 
+func (store *PostgresEventStore) FetchAllSince(
+	ctx context.Context,
+	actorName string,
+	eventUUID uuid.UUID,
+	limit int,
+	types storage.TypeMap) ([]storage.EventRecord, error) {
+	query, _ := store.Templates.Execute(
+		"select_all_events_since.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(
+		ctx,
+		query,
+		eventUUID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []storage.EventRecord{}
+	for rows.Next() {
+		buffer := []byte{}
+		var tx int64
+		err := rows.Scan(nil, nil, nil, &buffer, nil, &tx)
+		if err != nil {
+			return nil, err
+		}
+		record, err := spry.FromJson[storage.EventRecord](buffer)
+		if err != nil {
+			return nil, err
+		}
+		record, err = types.DecodeEvent(record)
+		if err != nil {
+			return nil, err
+		}
+		record.Position = storage.EventPosition{Tx: uint64(tx), Id: record.Id}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
 func (store *PostgresEventStore) FetchSince(
 	ctx context.Context,
 	actorName string,
@@ -97,10 +212,51 @@ func (store *PostgresEventStore) FetchSince(
 		return nil, err
 	}
 	defer rows.Close()
+	return readEvents(rows, types)
+}
+
+// the events a child created during the periods it was linked
+func (store *PostgresEventStore) fetchWithin(
+	ctx context.Context,
+	actorName string,
+	actorId uuid.UUID,
+	eventUUID uuid.UUID,
+	periods []storage.LinkPeriod,
+	types storage.TypeMap) ([]storage.EventRecord, error) {
+	query, _ := store.Templates.Execute(
+		"select_events_within.sql",
+		queryData(actorName),
+	)
+	startingOn := make([]time.Time, len(periods))
+	// a null end leaves the active link open
+	endingOn := make([]*time.Time, len(periods))
+	for i, period := range periods {
+		startingOn[i] = period.StartingOn
+		if !period.EndingOn.IsZero() {
+			endingOn[i] = &periods[i].EndingOn
+		}
+	}
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(
+		ctx,
+		query,
+		actorId,
+		eventUUID,
+		startingOn,
+		endingOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return readEvents(rows, types)
+}
+
+func readEvents(rows pgx.Rows, types storage.TypeMap) ([]storage.EventRecord, error) {
 	records := []storage.EventRecord{}
 	for rows.Next() {
 		buffer := []byte{}
-		err = rows.Scan(nil, nil, nil, &buffer, nil)
+		err := rows.Scan(nil, nil, nil, &buffer, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -108,11 +264,11 @@ func (store *PostgresEventStore) FetchSince(
 		if err != nil {
 			return nil, err
 		}
-		record.Data, err = types.AsEvent(record.Type, record.Data)
+		record, err = types.DecodeEvent(record)
 		if err != nil {
 			return nil, err
 		}
 		records = append(records, record)
 	}
-	return records, nil
+	return records, rows.Err()
 }
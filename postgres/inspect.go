@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/storage"
+)
+
+// a stored command or event as it was written
+type InspectedRecord struct {
+	Id        uuid.UUID       `json:"id"`
+	CreatedOn time.Time       `json:"createdOn"`
+	Version   uint64          `json:"version"`
+	Content   json.RawMessage `json:"content"`
+}
+
+type InspectedLink struct {
+	ChildType  string     `json:"childType"`
+	ChildId    uuid.UUID  `json:"childId"`
+	Active     bool       `json:"active"`
+	StartingOn *time.Time `json:"startingOn,omitempty"`
+	EndingOn   *time.Time `json:"endingOn,omitempty"`
+}
+
+// everything stored for one actor, read without knowing its types
+type Inspection struct {
+	ActorName   string           `json:"actor"`
+	ActorId     uuid.UUID        `json:"actorId"`
+	Identifiers spry.Identifiers `json:"identifiers"`
+	// the latest snapshot, null when the actor has none
+	Snapshot json.RawMessage `json:"snapshot"`
+	// the events after the latest snapshot
+	Events []InspectedRecord `json:"events"`
+	// the children linked to an aggregate, empty for other actors
+	Links []InspectedLink `json:"links"`
+	// every command the actor handled
+	Commands []InspectedRecord `json:"commands"`
+}
+
+// reads an actor's records straight from its tables for debugging. The
+// records are returned as stored, so personal data stays encrypted.
+type Inspector struct {
+	Pool      *pgxpool.Pool
+	Templates storage.StringTemplate
+}
+
+func CreateInspector(ctx context.Context, connectionURI string) (*Inspector, error) {
+	templates, err := storage.CreateTemplateFromFS(
+		sqlFiles,
+		"sql/select_commands_for_actor.sql",
+		"sql/select_events_since.sql",
+		"sql/select_id_by_map.sql",
+		"sql/select_latest_snapshot.sql",
+		"sql/select_links_for_actor.sql",
+	)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.Connect(ctx, connectionURI)
+	if err != nil {
+		return nil, err
+	}
+	return &Inspector{Pool: pool, Templates: *templates}, nil
+}
+
+func (inspector *Inspector) Close() {
+	inspector.Pool.Close()
+}
+
+func (inspector *Inspector) Inspect(ctx context.Context, actorName string, ids spry.Identifiers) (Inspection, error) {
+	inspection := Inspection{
+		ActorName:   actorName,
+		Identifiers: ids,
+		Snapshot:    json.RawMessage("null"),
+		Events:      []InspectedRecord{},
+		Links:       []InspectedLink{},
+		Commands:    []InspectedRecord{},
+	}
+	tx, err := inspector.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return inspection, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	data, err := spry.ToJson(ids)
+	if err != nil {
+		return inspection, err
+	}
+	query, _ := inspector.Templates.Execute("select_id_by_map.sql", queryData(actorName))
+	err = tx.QueryRow(ctx, query, data).Scan(nil, nil, &inspection.ActorId, nil)
+	if err == pgx.ErrNoRows {
+		return inspection, fmt.Errorf("no %s is identified by %s", actorName, data)
+	}
+	if err != nil {
+		return inspection, err
+	}
+
+	lastEventId := uuid.Nil
+	query, _ = inspector.Templates.Execute("select_latest_snapshot.sql", queryData(actorName))
+	err = tx.QueryRow(ctx, query, inspection.ActorId).Scan(nil, &inspection.Snapshot, nil, nil, &lastEventId, nil, nil)
+	if err != nil && err != pgx.ErrNoRows {
+		return inspection, err
+	}
+
+	inspection.Events, err = inspector.readRecords(ctx, tx, "select_events_since.sql", actorName, inspection.ActorId, lastEventId)
+	if err != nil {
+		return inspection, err
+	}
+	inspection.Commands, err = inspector.readRecords(ctx, tx, "select_commands_for_actor.sql", actorName, inspection.ActorId)
+	if err != nil {
+		return inspection, err
+	}
+	inspection.Links, err = inspector.readLinks(ctx, tx, actorName, inspection.ActorId)
+	return inspection, err
+}
+
+// reads rows of id, actor_id, created_on, content and version
+func (inspector *Inspector) readRecords(ctx context.Context, tx pgx.Tx, template string, actorName string, args ...any) ([]InspectedRecord, error) {
+	query, _ := inspector.Templates.Execute(template, queryData(actorName))
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []InspectedRecord{}
+	for rows.Next() {
+		record := InspectedRecord{}
+		err = rows.Scan(&record.Id, nil, &record.CreatedOn, &record.Content, &record.Version)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (inspector *Inspector) readLinks(ctx context.Context, tx pgx.Tx, actorName string, actorId uuid.UUID) ([]InspectedLink, error) {
+	query, _ := inspector.Templates.Execute("select_links_for_actor.sql", queryData(actorName))
+	rows, err := tx.Query(ctx, query, actorName, actorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	links := []InspectedLink{}
+	for rows.Next() {
+		link := InspectedLink{}
+		err = rows.Scan(nil, nil, &link.ChildType, &link.ChildId, &link.Active, &link.StartingOn, &link.EndingOn)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
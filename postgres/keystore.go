@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/legitbiz/spry/storage"
+)
+
+type PostgresKeyStore struct {
+	Pool      *pgxpool.Pool
+	Templates storage.StringTemplate
+}
+
+func (store *PostgresKeyStore) Add(ctx context.Context, actorName string, actorId uuid.UUID, key []byte) error {
+	query, _ := store.Templates.Execute(
+		"insert_key.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, actorId, key, time.Now())
+	return err
+}
+
+func (store *PostgresKeyStore) Delete(ctx context.Context, actorName string, actorId uuid.UUID) error {
+	query, _ := store.Templates.Execute(
+		"delete_key.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, actorId)
+	return err
+}
+
+func (store *PostgresKeyStore) Fetch(ctx context.Context, actorName string, actorId uuid.UUID) ([]byte, error) {
+	query, _ := store.Templates.Execute(
+		"select_key.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(ctx, query, actorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var key []byte
+	if rows.Next() {
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, rows.Err()
+}
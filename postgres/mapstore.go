@@ -51,6 +51,9 @@ func (store *PostgresMapStore) AddLink(ctx context.Context, parentType string, p
 	)
 	tx := storage.GetTx[pgx.Tx](ctx)
 	id, _ := storage.GetId()
+	// links are bounded against event times, which come from the
+	// application's clock rather than the database's
+	startingOn := time.Now().UTC()
 	err := tx.BeginFunc(
 		ctx,
 		func(t pgx.Tx) error {
@@ -62,6 +65,7 @@ func (store *PostgresMapStore) AddLink(ctx context.Context, parentType string, p
 				parentId,
 				childType,
 				childId,
+				startingOn,
 			)
 			return err
 		},
@@ -123,12 +127,150 @@ func (store *PostgresMapStore) GetIdMap(ctx context.Context, actorName string, u
 	for rows.Next() {
 		var child string
 		var id uuid.UUID
-		err = rows.Scan(nil, nil, &child, &id)
+		var active bool
+		var startingOn time.Time
+		var endingOn *time.Time
+		err = rows.Scan(nil, nil, &child, &id, &active, &startingOn, &endingOn)
 		if err != nil {
 			return empty, err
 		}
-		idMap.AddIdsFor(child, id)
+		if active {
+			idMap.AddIdsFor(child, id)
+			idMap.AddLinkPeriod(child, id, startingOn, time.Time{})
+		} else if endingOn != nil {
+			idMap.AddLinkPeriod(child, id, startingOn, *endingOn)
+		}
+	}
+
+	return idMap, rows.Err()
+}
+
+func (store *PostgresMapStore) GetActorIds(ctx context.Context, actorName string) ([]uuid.UUID, error) {
+	query, _ := store.Templates.Execute(
+		"select_actor_ids.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (store *PostgresMapStore) GetIdHistory(ctx context.Context, actorName string, uid uuid.UUID) ([]storage.IdentifierRecord, error) {
+	query, _ := store.Templates.Execute(
+		"select_id_history.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(ctx, query, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []storage.IdentifierRecord{}
+	for rows.Next() {
+		var data []byte
+		var endingOn *time.Time
+		record := storage.IdentifierRecord{}
+		err = rows.Scan(&data, &record.StartingOn, &endingOn, &record.Retired)
+		if err != nil {
+			return nil, err
+		}
+		record.Identifiers, err = spry.FromJson[spry.Identifiers](data)
+		if err != nil {
+			return nil, err
+		}
+		if endingOn != nil {
+			record.EndingOn = *endingOn
+		}
+		history = append(history, record)
+	}
+	return history, rows.Err()
+}
+
+func (store *PostgresMapStore) Rekey(
+	ctx context.Context,
+	actorName string,
+	uid uuid.UUID,
+	ids spry.Identifiers,
+	retire bool,
+	on time.Time) error {
+	ended, _ := store.Templates.Execute(
+		"update_id_map_ended.sql",
+		queryData(actorName),
+	)
+	current, _ := store.Templates.Execute(
+		"insert_current_map.sql",
+		queryData(actorName),
+	)
+	data, err := spry.ToJson(ids)
+	if err != nil {
+		return err
 	}
+	id, _ := storage.GetId()
+	tx := storage.GetTx[pgx.Tx](ctx)
+	return tx.BeginFunc(
+		ctx,
+		func(t pgx.Tx) error {
+			_, err := t.Exec(ctx, ended, uid, on, retire, data)
+			if err != nil {
+				return err
+			}
+			inserted, err := t.Exec(ctx, current, id, data, uid, on)
+			if err != nil {
+				return err
+			}
+			if inserted.RowsAffected() == 0 {
+				return storage.ErrIdentifiersInUse{ActorName: actorName, Identifiers: ids}
+			}
+			return nil
+		},
+	)
+}
+
+func (store *PostgresMapStore) RetireIds(ctx context.Context, actorName string, uid uuid.UUID, on time.Time) error {
+	query, _ := store.Templates.Execute(
+		"update_id_map_retired.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, uid, on)
+	return err
+}
 
-	return idMap, nil
+func (store *PostgresMapStore) RemoveLink(
+	ctx context.Context,
+	parentType string,
+	parentId uuid.UUID,
+	childType string,
+	childId uuid.UUID,
+	endingOn time.Time) error {
+	query, _ := store.Templates.Execute(
+		"update_link_ended.sql",
+		queryData(parentType),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(
+		ctx,
+		query,
+		parentId,
+		childType,
+		childId,
+		endingOn,
+	)
+	return err
 }
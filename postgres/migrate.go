@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/legitbiz/spry/storage"
+)
+
+// a versioned change to an actor's tables. Up and Down name templates
+// in the sql folder. Once a migration has been released it shouldn't
+// change; changes to the schema are made by adding the next migration.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// reverting drops stored records, so Down refuses unless the
+	// options allow it
+	DestroysData bool
+}
+
+// every Up uses IF NOT EXISTS, so tables created before migrations were
+// tracked are brought up to date rather than failing
+var Migrations = []Migration{
+	{
+		Version:      1,
+		Name:         "actor_schema",
+		Up:           "create_actor_schema.sql",
+		Down:         "drop_actor_schema.sql",
+		DestroysData: true,
+	},
+	{Version: 2, Name: "event_versions", Up: "create_event_version_index.sql", Down: "drop_event_version_index.sql"},
+	{Version: 3, Name: "outbox", Up: "create_outbox.sql", Down: "drop_outbox.sql", DestroysData: true},
+	{Version: 4, Name: "link_periods", Up: "alter_links_add_periods.sql", Down: "alter_links_drop_periods.sql"},
+	{Version: 5, Name: "id_periods", Up: "alter_id_map_add_periods.sql", Down: "alter_id_map_drop_periods.sql"},
+	{Version: 6, Name: "keys", Up: "create_keys.sql", Down: "drop_keys.sql", DestroysData: true},
+	{
+		Version: 7,
+		Name:    "superseded_snapshots",
+		Up:      "alter_snapshots_add_superseded.sql",
+		Down:    "alter_snapshots_drop_superseded.sql",
+	},
+	{
+		Version: 8,
+		Name:    "event_positions",
+		Up:      "alter_events_add_positions.sql",
+		Down:    "alter_events_drop_positions.sql",
+	},
+}
+
+// returned by Down instead of reverting a migration that drops records
+var ErrMigrationDestroysData = errors.New("reverting the migration would destroy stored data")
+
+type MigrateDownOptions struct {
+	// the number of migrations to revert for each actor
+	Steps int
+	// allows reverting migrations that drop tables holding records
+	DestroyData bool
+}
+
+type MigrationStatus struct {
+	ActorName string
+	Migration
+	// zero when the migration hasn't been applied
+	AppliedOn time.Time
+}
+
+func (status MigrationStatus) IsApplied() bool {
+	return !status.AppliedOn.IsZero()
+}
+
+// applies and reverts Migrations for actors, recording what has been
+// applied to each in the spry_migrations table
+type Migrator struct {
+	Pool      *pgxpool.Pool
+	Templates storage.StringTemplate
+}
+
+func loadMigrationTemplates() (*storage.StringTemplate, error) {
+	files := []string{
+		"sql/create_migrations.sql",
+		"sql/delete_migration.sql",
+		"sql/insert_migration.sql",
+		"sql/select_migrations.sql",
+	}
+	for _, migration := range Migrations {
+		files = append(files, "sql/"+migration.Up, "sql/"+migration.Down)
+	}
+	return storage.CreateTemplateFromFS(sqlFiles, files...)
+}
+
+func CreateMigrator(ctx context.Context, connectionURI string) (*Migrator, error) {
+	templates, err := loadMigrationTemplates()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.Connect(ctx, connectionURI)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{Pool: pool, Templates: *templates}, nil
+}
+
+func (migrator *Migrator) Close() {
+	migrator.Pool.Close()
+}
+
+// table names are always lower case
+func migrationData(actorName string) QueryData {
+	return queryData(strings.ToLower(actorName))
+}
+
+// runs fn in a transaction that holds the lock on spry_migrations, so
+// that only one migrator changes the schema at a time
+func (migrator *Migrator) locked(ctx context.Context, fn func(pgx.Tx) error) error {
+	return migrator.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('spry_migrations'))")
+		if err != nil {
+			return err
+		}
+		create, _ := migrator.Templates.Execute("create_migrations.sql", nil)
+		_, err = tx.Exec(ctx, create)
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func (migrator *Migrator) fetchStatus(ctx context.Context, tx pgx.Tx, actorName string) ([]MigrationStatus, error) {
+	query, _ := migrator.Templates.Execute("select_migrations.sql", nil)
+	rows, err := tx.Query(ctx, query, strings.ToLower(actorName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedOn time.Time
+		err = rows.Scan(&version, &appliedOn)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedOn
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(Migrations))
+	for i, migration := range Migrations {
+		statuses[i] = MigrationStatus{
+			ActorName: actorName,
+			Migration: migration,
+			AppliedOn: applied[migration.Version],
+		}
+	}
+	return statuses, nil
+}
+
+func (migrator *Migrator) run(ctx context.Context, tx pgx.Tx, template string, actorName string) error {
+	script, err := migrator.Templates.Execute(template, migrationData(actorName))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, script)
+	if err != nil {
+		return fmt.Errorf("failed to run %s for %s: %w", template, actorName, err)
+	}
+	return nil
+}
+
+// the state of every migration for each actor
+func (migrator *Migrator) Status(ctx context.Context, actorNames ...string) ([]MigrationStatus, error) {
+	statuses := []MigrationStatus{}
+	err := migrator.locked(ctx, func(tx pgx.Tx) error {
+		for _, actorName := range actorNames {
+			status, err := migrator.fetchStatus(ctx, tx, actorName)
+			if err != nil {
+				return err
+			}
+			statuses = append(statuses, status...)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// applies every migration the actors don't have yet, in order, and
+// returns the ones applied. Nothing is applied if any of them fail.
+func (migrator *Migrator) Up(ctx context.Context, actorNames ...string) ([]MigrationStatus, error) {
+	applied := []MigrationStatus{}
+	err := migrator.locked(ctx, func(tx pgx.Tx) error {
+		insert, _ := migrator.Templates.Execute("insert_migration.sql", nil)
+		for _, actorName := range actorNames {
+			statuses, err := migrator.fetchStatus(ctx, tx, actorName)
+			if err != nil {
+				return err
+			}
+			for _, status := range statuses {
+				if status.IsApplied() {
+					continue
+				}
+				err = migrator.run(ctx, tx, status.Up, actorName)
+				if err != nil {
+					return err
+				}
+				status.AppliedOn = time.Now().UTC()
+				_, err = tx.Exec(ctx, insert, strings.ToLower(actorName), status.Version, status.Name, status.AppliedOn)
+				if err != nil {
+					return err
+				}
+				applied = append(applied, status)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// reverts the latest migrations applied to each actor, newest first, and
+// returns the ones reverted. Nothing is reverted if any of them would
+// destroy data and the options don't allow it.
+func (migrator *Migrator) Down(ctx context.Context, opts MigrateDownOptions, actorNames ...string) ([]MigrationStatus, error) {
+	reverted := []MigrationStatus{}
+	err := migrator.locked(ctx, func(tx pgx.Tx) error {
+		remove, _ := migrator.Templates.Execute("delete_migration.sql", nil)
+		for _, actorName := range actorNames {
+			statuses, err := migrator.fetchStatus(ctx, tx, actorName)
+			if err != nil {
+				return err
+			}
+			count := 0
+			for i := len(statuses) - 1; i >= 0 && count < opts.Steps; i-- {
+				status := statuses[i]
+				if !status.IsApplied() {
+					continue
+				}
+				if status.DestroysData && !opts.DestroyData {
+					return fmt.Errorf(
+						"%04d_%s for %s: %w",
+						status.Version,
+						status.Name,
+						actorName,
+						ErrMigrationDestroysData,
+					)
+				}
+				err = migrator.run(ctx, tx, status.Down, actorName)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec(ctx, remove, strings.ToLower(actorName), status.Version)
+				if err != nil {
+					return err
+				}
+				reverted = append(reverted, status)
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
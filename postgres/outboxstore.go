@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/storage"
+)
+
+type PostgresOutboxStore struct {
+	Pool      *pgxpool.Pool
+	Templates storage.StringTemplate
+}
+
+func (store *PostgresOutboxStore) Add(ctx context.Context, events []storage.EventRecord) error {
+	tx := storage.GetTx[pgx.Tx](ctx)
+	batch := pgx.Batch{}
+	for _, event := range events {
+		data, err := spry.ToJson(event)
+		if err != nil {
+			return err
+		}
+		query, _ := store.Templates.Execute(
+			"insert_outbox.sql",
+			queryData(event.ActorName),
+		)
+		batch.Queue(
+			query,
+			event.Id,
+			event.ActorId,
+			data,
+			event.CreatedOn,
+		)
+	}
+
+	results := tx.SendBatch(ctx, &batch)
+	for range events {
+		if _, err := results.Exec(); err != nil {
+			_ = results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
+// pending rows stay locked until the relay's transaction ends so
+// that concurrent relays never publish the same rows
+func (store *PostgresOutboxStore) FetchPending(
+	ctx context.Context,
+	actorName string,
+	limit int,
+	types storage.TypeMap) ([]storage.OutboxRecord, error) {
+	query, _ := store.Templates.Execute(
+		"select_pending_outbox.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	rows, err := tx.Query(
+		ctx,
+		query,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.OutboxRecord{}
+	for rows.Next() {
+		buffer := []byte{}
+		record := storage.OutboxRecord{}
+		err := rows.Scan(nil, &buffer, &record.Attempts, &record.NextAttemptOn)
+		if err != nil {
+			return nil, err
+		}
+		record.Event, err = spry.FromJson[storage.EventRecord](buffer)
+		if err != nil {
+			return nil, err
+		}
+		record.Event, err = types.DecodeEvent(record.Event)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (store *PostgresOutboxStore) MarkPublished(ctx context.Context, actorName string, eventId uuid.UUID) error {
+	query, _ := store.Templates.Execute(
+		"delete_outbox_record.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, eventId)
+	return err
+}
+
+func (store *PostgresOutboxStore) MarkUnpublished(ctx context.Context, actorName string, eventId uuid.UUID, nextAttempt time.Time) error {
+	query, _ := store.Templates.Execute(
+		"update_outbox_unpublished.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, eventId, nextAttempt)
+	return err
+}
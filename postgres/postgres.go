@@ -31,15 +31,44 @@ func CreatePostgresStorage(connectionURI string) storage.Storage {
 	// load templates
 	templates, err := storage.CreateTemplateFromFS(
 		sqlFiles,
+		"sql/delete_key.sql",
+		"sql/delete_outbox_record.sql",
+		"sql/insert_archived_command.sql",
+		"sql/insert_archived_event.sql",
+		"sql/insert_archived_id.sql",
+		"sql/insert_archived_link.sql",
+		"sql/insert_archived_snapshot.sql",
 		"sql/insert_command.sql",
+		"sql/insert_current_map.sql",
 		"sql/insert_event.sql",
+		"sql/insert_key.sql",
 		"sql/insert_link.sql",
 		"sql/insert_map.sql",
+		"sql/insert_outbox.sql",
 		"sql/insert_snapshot.sql",
+		"sql/select_actor_ids.sql",
+		"sql/select_all_events_since.sql",
+		"sql/select_archived_commands.sql",
+		"sql/select_archived_events.sql",
+		"sql/select_archived_ids.sql",
+		"sql/select_archived_links.sql",
+		"sql/select_archived_snapshots.sql",
+		"sql/select_commands.sql",
 		"sql/select_events_since.sql",
+		"sql/select_events_within.sql",
 		"sql/select_id_by_map.sql",
+		"sql/select_id_history.sql",
+		"sql/select_key.sql",
 		"sql/select_latest_snapshot.sql",
 		"sql/select_links_for_actor.sql",
+		"sql/select_pending_outbox.sql",
+		"sql/select_snapshot_as_of.sql",
+		"sql/select_snapshot_at_version.sql",
+		"sql/update_id_map_ended.sql",
+		"sql/update_id_map_retired.sql",
+		"sql/update_link_ended.sql",
+		"sql/update_outbox_unpublished.sql",
+		"sql/update_snapshots_superseded.sql",
 	)
 
 	if err != nil {
@@ -47,11 +76,38 @@ func CreatePostgresStorage(connectionURI string) storage.Storage {
 		panic("oh no")
 	}
 
-	return storage.NewStorage[pgx.Tx](
+	stores := storage.NewStorage[pgx.Tx](
 		&PostgresCommandStore{Templates: *templates, Pool: pool},
 		&PostgresEventStore{Templates: *templates, Pool: pool},
 		&PostgresMapStore{Templates: *templates, Pool: pool},
 		&PostgresSnapshotStore{Templates: *templates, Pool: pool},
 		&PostgresTxProvider{Pool: pool},
-	)
+	).(storage.Stores[pgx.Tx])
+	stores.Archive = &PostgresArchiveStore{Templates: *templates, Pool: pool}
+	return stores
+}
+
+// the outbox for a storage created by CreatePostgresStorage, sharing its
+// pool. Pass it to RegisterOutbox once the outbox migration has run.
+func CreatePostgresOutboxStore(store storage.Storage) storage.OutboxStore {
+	events := eventStoreOf(store)
+	return &PostgresOutboxStore{Templates: events.Templates, Pool: events.Pool}
+}
+
+// the key store for a storage created by CreatePostgresStorage, sharing its
+// pool. Pass it to RegisterKeyStore once the keys migration has run.
+func CreatePostgresKeyStore(store storage.Storage) storage.KeyStore {
+	events := eventStoreOf(store)
+	return &PostgresKeyStore{Templates: events.Templates, Pool: events.Pool}
+}
+
+func eventStoreOf(store storage.Storage) *PostgresEventStore {
+	stores, ok := store.(storage.Stores[pgx.Tx])
+	if ok {
+		events, ok := stores.Events.(*PostgresEventStore)
+		if ok {
+			return events
+		}
+	}
+	panic("storage was not created by CreatePostgresStorage")
 }
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v4"
@@ -46,16 +47,38 @@ func (store *PostgresSnapshotStore) Add(ctx context.Context, actorName string, s
 	return err
 }
 
+func (store *PostgresSnapshotStore) Supersede(ctx context.Context, actorName string, actorId uuid.UUID, on time.Time) error {
+	query, _ := store.Templates.Execute(
+		"update_snapshots_superseded.sql",
+		queryData(actorName),
+	)
+	tx := storage.GetTx[pgx.Tx](ctx)
+	_, err := tx.Exec(ctx, query, actorId, on)
+	return err
+}
+
 func (store *PostgresSnapshotStore) Fetch(ctx context.Context, actorName string, actorId uuid.UUID) (storage.Snapshot, error) {
+	return store.fetchOne(ctx, "select_latest_snapshot.sql", actorName, actorId)
+}
+
+func (store *PostgresSnapshotStore) FetchAsOf(ctx context.Context, actorName string, actorId uuid.UUID, asOf time.Time) (storage.Snapshot, error) {
+	return store.fetchOne(ctx, "select_snapshot_as_of.sql", actorName, actorId, asOf)
+}
+
+func (store *PostgresSnapshotStore) FetchAtVersion(ctx context.Context, actorName string, actorId uuid.UUID, version uint64) (storage.Snapshot, error) {
+	return store.fetchOne(ctx, "select_snapshot_at_version.sql", actorName, actorId, version)
+}
+
+func (store *PostgresSnapshotStore) fetchOne(ctx context.Context, template string, actorName string, args ...any) (storage.Snapshot, error) {
 	query, _ := store.Templates.Execute(
-		"select_latest_snapshot.sql",
+		template,
 		queryData(actorName),
 	)
 	tx := storage.GetTx[pgx.Tx](ctx)
 	rows, err := tx.Query(
 		ctx,
 		query,
-		actorId,
+		args...,
 	)
 	if err != nil {
 		return storage.Snapshot{}, err
@@ -0,0 +1,6 @@
+-- the transaction that wrote each event orders the feed, since ids are
+-- assigned before commit. Events already stored share this migration's
+-- transaction and keep their id order.
+ALTER TABLE {{.ActorName}}_events ADD COLUMN IF NOT EXISTS tx_id bigint NOT NULL DEFAULT txid_current();
+
+CREATE INDEX IF NOT EXISTS {{.ActorName}}_event_position_idx on {{.ActorName}}_events(tx_id, id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS {{.ActorName}}_event_position_idx;
+
+ALTER TABLE {{.ActorName}}_events DROP COLUMN IF EXISTS tx_id;
\ No newline at end of file
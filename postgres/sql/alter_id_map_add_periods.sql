@@ -0,0 +1,3 @@
+-- identifiers were permanent
+ALTER TABLE {{.ActorName}}_id_map ADD COLUMN IF NOT EXISTS ending_on timestamp with time zone;
+ALTER TABLE {{.ActorName}}_id_map ADD COLUMN IF NOT EXISTS retired bool NOT NULL DEFAULT(false);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE {{.ActorName}}_id_map DROP COLUMN IF EXISTS retired;
+ALTER TABLE {{.ActorName}}_id_map DROP COLUMN IF EXISTS ending_on;
\ No newline at end of file
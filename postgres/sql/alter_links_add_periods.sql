@@ -0,0 +1,5 @@
+-- links were unique per child and had no end
+ALTER TABLE {{.ActorName}}_links ADD COLUMN IF NOT EXISTS ending_on timestamp with time zone;
+ALTER TABLE {{.ActorName}}_links DROP CONSTRAINT IF EXISTS {{.ActorName}}_links_parent_id_child_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{.ActorName}}_link_active_idx on {{.ActorName}}_links(parent_id, child_id) WHERE active;
\ No newline at end of file
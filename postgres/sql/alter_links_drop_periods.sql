@@ -0,0 +1,4 @@
+-- fails while a child has more than one link period to a parent
+DROP INDEX IF EXISTS {{.ActorName}}_link_active_idx;
+ALTER TABLE {{.ActorName}}_links ADD CONSTRAINT {{.ActorName}}_links_parent_id_child_id_key UNIQUE(parent_id, child_id);
+ALTER TABLE {{.ActorName}}_links DROP COLUMN IF EXISTS ending_on;
\ No newline at end of file
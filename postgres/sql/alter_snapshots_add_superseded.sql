@@ -0,0 +1 @@
+ALTER TABLE {{.ActorName}}_snapshots ADD COLUMN IF NOT EXISTS superseded_on timestamp with time zone;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE {{.ActorName}}_snapshots DROP COLUMN IF EXISTS superseded_on;
\ No newline at end of file
@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS {{.ActorName}}_event_version_idx;
+
+-- events written before streams were versioned share the version of the
+-- snapshot that created them, so each stream is numbered by id first
+UPDATE {{.ActorName}}_events AS e
+SET
+    version = numbered.version,
+    content = jsonb_set(e.content, '{version}', to_jsonb(numbered.version))
+FROM (
+    SELECT
+        id,
+        row_number() OVER (PARTITION BY actor_id ORDER BY id) AS version
+    FROM {{.ActorName}}_events
+) AS numbered
+WHERE
+    e.id = numbered.id
+    AND e.version <> numbered.version;
+
+CREATE UNIQUE INDEX IF NOT EXISTS {{.ActorName}}_event_version_idx on {{.ActorName}}_events(actor_id, version);
\ No newline at end of file
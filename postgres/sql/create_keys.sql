@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS {{.ActorName}}_keys (
+    actor_id        uuid            PRIMARY KEY,
+    key             bytea           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now()
+);
\ No newline at end of file
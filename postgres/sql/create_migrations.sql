@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS spry_migrations (
+    actor_name      varchar(128)    NOT NULL,
+    version         int             NOT NULL,
+    name            varchar(256)    NOT NULL,
+    applied_on      timestamp with time zone            DEFAULT now(),
+    PRIMARY KEY(actor_name, version)
+);
\ No newline at end of file
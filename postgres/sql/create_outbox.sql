@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS {{.ActorName}}_outbox (
+    id              uuid            PRIMARY KEY,
+    actor_id        uuid            NOT NULL,
+    content         jsonb           NOT NULL,
+    created_on      timestamp with time zone            DEFAULT now(),
+    attempts        int             NOT NULL DEFAULT 0,
+    next_attempt_on timestamp with time zone            DEFAULT now(),
+    published_on    timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS {{.ActorName}}_outbox_pending_idx on {{.ActorName}}_outbox(next_attempt_on) WHERE published_on IS NULL;
\ No newline at end of file
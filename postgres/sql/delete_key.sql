@@ -0,0 +1,2 @@
+DELETE FROM {{.ActorName}}_keys
+WHERE actor_id = $1;
\ No newline at end of file
@@ -0,0 +1,4 @@
+DELETE FROM spry_migrations
+WHERE
+    actor_name = $1
+    AND version = $2;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS {{.ActorName}}_snapshots;
+DROP TABLE IF EXISTS {{.ActorName}}_links;
+DROP TABLE IF EXISTS {{.ActorName}}_id_map;
+DROP TABLE IF EXISTS {{.ActorName}}_events;
+DROP TABLE IF EXISTS {{.ActorName}}_commands;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS {{.ActorName}}_event_version_idx;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.ActorName}}_keys;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.ActorName}}_outbox;
\ No newline at end of file
@@ -0,0 +1,10 @@
+INSERT INTO {{.ActorName}}_commands (
+    id,
+    actor_id,
+    content,
+    created_on,
+    version
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,11 @@
+INSERT INTO {{.ActorName}}_id_map (
+    id,
+    identifiers,
+    actor_id,
+    starting_on,
+    ending_on,
+    retired
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,13 @@
+INSERT INTO {{.ActorName}}_links (
+    id,
+    parent_type,
+    parent_id,
+    child_type,
+    child_id,
+    active,
+    starting_on,
+    ending_on
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- identifiers another actor currently holds are left alone, so
+-- nothing is inserted
+INSERT INTO {{.ActorName}}_id_map (
+    id,
+    identifiers,
+    actor_id,
+    starting_on
+)
+SELECT $1::uuid, $2::jsonb, $3::uuid, $4::timestamp with time zone
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM {{.ActorName}}_id_map
+    WHERE
+        identifiers = $2::jsonb
+        AND actor_id <> $3::uuid
+        AND ending_on IS NULL
+        AND NOT retired
+)
+ON CONFLICT (identifiers, actor_id) DO UPDATE
+SET
+    starting_on = EXCLUDED.starting_on,
+    ending_on = NULL,
+    retired = false;
\ No newline at end of file
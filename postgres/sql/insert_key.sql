@@ -0,0 +1,8 @@
+INSERT INTO {{.ActorName}}_keys (
+    actor_id,
+    key,
+    created_on
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
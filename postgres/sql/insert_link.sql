@@ -3,8 +3,9 @@ INSERT INTO {{.ActorName}}_links (
     parent_type,
     parent_id,
     child_type,
-    child_id
+    child_id,
+    starting_on
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
     )
-ON CONFLICT DO NOTHING;
\ No newline at end of file
+ON CONFLICT (parent_id, child_id) WHERE active DO NOTHING;
\ No newline at end of file
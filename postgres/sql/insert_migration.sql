@@ -0,0 +1,8 @@
+INSERT INTO spry_migrations (
+    actor_name,
+    version,
+    name,
+    applied_on
+) VALUES (
+    $1, $2, $3, $4
+);
\ No newline at end of file
@@ -0,0 +1,10 @@
+INSERT INTO {{.ActorName}}_outbox (
+    id,
+    actor_id,
+    content,
+    created_on,
+    next_attempt_on
+) VALUES (
+    $1, $2, $3, $4, $4
+)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,4 @@
+SELECT DISTINCT
+    actor_id
+FROM {{.ActorName}}_id_map
+ORDER BY actor_id ASC;
\ No newline at end of file
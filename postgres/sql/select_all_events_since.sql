@@ -0,0 +1,25 @@
+SELECT
+    id,
+    actor_id,
+    created_on,
+    content,
+    version,
+    tx_id
+FROM {{.ActorName}}_events
+WHERE
+    (tx_id, id) > (
+        SELECT COALESCE(max(tx_id), 0), $1::uuid
+        FROM {{.ActorName}}_events
+        WHERE id = $1
+    )
+    -- a transaction still in progress could commit events before any
+    -- written after it started, so the feed stops short of them. This is
+    -- chosen over reading whatever LISTEN/NOTIFY reports with a bounded
+    -- lag: a notification only says one transaction committed, and any
+    -- transaction open longer than the lag would have its events skipped
+    -- for good once the checkpoint moved past them. Gating on xmin never
+    -- skips an event, at the cost of stalling while any writing
+    -- transaction in the database is open.
+    AND tx_id < txid_snapshot_xmin(txid_current_snapshot())
+ORDER BY tx_id ASC, id ASC
+LIMIT NULLIF($2, 0);
\ No newline at end of file
@@ -0,0 +1,4 @@
+SELECT
+    content
+FROM {{.ActorName}}_events
+ORDER BY id ASC;
\ No newline at end of file
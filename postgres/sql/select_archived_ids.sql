@@ -0,0 +1,9 @@
+SELECT
+    id,
+    identifiers,
+    actor_id,
+    starting_on,
+    ending_on,
+    retired
+FROM {{.ActorName}}_id_map
+ORDER BY starting_on ASC, id ASC;
\ No newline at end of file
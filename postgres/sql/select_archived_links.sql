@@ -0,0 +1,10 @@
+SELECT
+    id,
+    parent_type,
+    parent_id,
+    child_type,
+    child_id,
+    starting_on,
+    ending_on
+FROM {{.ActorName}}_links
+ORDER BY starting_on ASC, id ASC;
\ No newline at end of file
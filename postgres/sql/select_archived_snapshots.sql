@@ -0,0 +1,6 @@
+SELECT
+    content
+FROM {{.ActorName}}_snapshots
+WHERE
+    superseded_on IS NULL
+ORDER BY id ASC;
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT
+    content
+FROM {{.ActorName}}_commands
+WHERE
+    id = ANY($1::uuid[]);
\ No newline at end of file
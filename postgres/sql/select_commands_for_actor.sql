@@ -0,0 +1,10 @@
+SELECT
+    id,
+    actor_id,
+    created_on,
+    content,
+    version
+FROM {{.ActorName}}_commands
+WHERE
+    actor_id = $1
+ORDER BY id ASC;
\ No newline at end of file
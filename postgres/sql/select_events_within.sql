@@ -0,0 +1,18 @@
+SELECT
+    id,
+    actor_id,
+    created_on,
+    content,
+    version
+FROM {{.ActorName}}_events
+WHERE
+    actor_id = $1 AND
+    id > $2 AND
+    EXISTS (
+        SELECT 1
+        FROM unnest($3::timestamptz[], $4::timestamptz[]) AS period(starting_on, ending_on)
+        WHERE
+            created_on >= period.starting_on AND
+            (period.ending_on IS NULL OR created_on <= period.ending_on)
+    )
+ORDER BY id ASC;
\ No newline at end of file
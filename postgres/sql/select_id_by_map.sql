@@ -6,5 +6,6 @@ SELECT
 FROM {{.ActorName}}_id_map
 WHERE
     identifiers = $1
-ORDER BY id DESC
+    AND NOT retired
+ORDER BY ending_on IS NULL DESC, id DESC
 LIMIT 1;
\ No newline at end of file
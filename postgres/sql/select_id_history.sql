@@ -0,0 +1,9 @@
+SELECT
+    identifiers,
+    starting_on,
+    ending_on,
+    retired
+FROM {{.ActorName}}_id_map
+WHERE
+    actor_id = $1
+ORDER BY starting_on ASC, id ASC;
\ No newline at end of file
@@ -0,0 +1,4 @@
+SELECT
+    key
+FROM {{.ActorName}}_keys
+WHERE actor_id = $1;
\ No newline at end of file
@@ -9,5 +9,6 @@ SELECT
 FROM {{.ActorName}}_snapshots
 WHERE
     actor_id = $1
+    AND superseded_on IS NULL
 ORDER BY id DESC
 LIMIT 1;
\ No newline at end of file
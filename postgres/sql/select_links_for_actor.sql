@@ -2,7 +2,10 @@ SELECT
     parent_type,
     parent_id,
     child_type,
-    child_id
+    child_id,
+    active,
+    starting_on,
+    ending_on
 FROM {{.ActorName}}_links
 WHERE
     parent_type = $1
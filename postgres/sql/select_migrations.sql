@@ -0,0 +1,7 @@
+SELECT
+    version,
+    applied_on
+FROM spry_migrations
+WHERE
+    actor_name = $1
+ORDER BY version ASC;
\ No newline at end of file
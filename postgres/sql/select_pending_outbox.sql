@@ -0,0 +1,12 @@
+SELECT
+    id,
+    content,
+    attempts,
+    next_attempt_on
+FROM {{.ActorName}}_outbox
+WHERE
+    published_on IS NULL AND
+    next_attempt_on <= now()
+ORDER BY id ASC
+LIMIT NULLIF($1, 0)
+FOR UPDATE SKIP LOCKED;
\ No newline at end of file
@@ -0,0 +1,15 @@
+SELECT
+    actor_id,
+    content,
+    last_command_id,
+    last_command_handled_on,
+    last_event_id,
+    last_event_applied_on,
+    version
+FROM {{.ActorName}}_snapshots
+WHERE
+    actor_id = $1
+    AND superseded_on IS NULL
+    AND version <= $2
+ORDER BY version DESC, id DESC
+LIMIT 1;
\ No newline at end of file
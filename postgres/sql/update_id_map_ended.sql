@@ -0,0 +1,8 @@
+UPDATE {{.ActorName}}_id_map
+SET
+    ending_on = $2,
+    retired = $3
+WHERE
+    actor_id = $1
+    AND ending_on IS NULL
+    AND identifiers <> $4;
\ No newline at end of file
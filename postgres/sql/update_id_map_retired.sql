@@ -0,0 +1,7 @@
+UPDATE {{.ActorName}}_id_map
+SET
+    ending_on = COALESCE(ending_on, $2),
+    retired = true
+WHERE
+    actor_id = $1
+    AND NOT retired;
\ No newline at end of file
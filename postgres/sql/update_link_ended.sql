@@ -0,0 +1,9 @@
+UPDATE {{.ActorName}}_links
+SET
+    active = false,
+    ending_on = $4
+WHERE
+    parent_id = $1
+    AND child_type = $2
+    AND child_id = $3
+    AND active;
\ No newline at end of file
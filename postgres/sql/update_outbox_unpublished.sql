@@ -0,0 +1,6 @@
+UPDATE {{.ActorName}}_outbox
+SET
+    attempts = attempts + 1,
+    next_attempt_on = $2
+WHERE
+    id = $1;
\ No newline at end of file
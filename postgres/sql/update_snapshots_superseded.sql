@@ -0,0 +1,5 @@
+UPDATE {{.ActorName}}_snapshots
+SET superseded_on = $2
+WHERE
+    actor_id = $1
+    AND superseded_on IS NULL;
\ No newline at end of file
@@ -1,12 +1,9 @@
 package postgres
 
 import (
-	"embed"
 	"fmt"
 	"strings"
 	"time"
-
-	"github.com/arobson/spry/storage"
 )
 
 var banner = `
@@ -20,29 +17,33 @@ var banner = `
 %s
 `
 
-//go:embed sql
-var pgSqlFiles embed.FS
-
+// the up scripts of every migration in order, for creating an actor's
+// tables by hand
 func PostgresGenerateActorSchema(actorName string) (string, error) {
-	type Data struct {
-		ActorName string
-	}
-	templates, err := storage.CreateTemplateFromFS(
-		pgSqlFiles,
-		"sql/create_actor_schema.sql",
-	)
+	return PostgresGenerateSchema(actorName)
+}
+
+// one script creating the tables of every actor, in the order given
+func PostgresGenerateSchema(actorNames ...string) (string, error) {
+	templates, err := loadMigrationTemplates()
 	if err != nil {
 		return "", error(fmt.Errorf("failed to create templates from embedded FS: %e", err))
 	}
-	result, err := templates.Execute(
-		"create_actor_schema.sql",
-		Data{ActorName: strings.ToLower(actorName)},
-	)
-	if err != nil {
-		return "", error(fmt.Errorf("failed to create schema from template: %e", err))
+	scripts := make([]string, 0, len(actorNames)*len(Migrations))
+	for _, actorName := range actorNames {
+		for _, migration := range Migrations {
+			script, err := templates.Execute(
+				migration.Up,
+				migrationData(actorName),
+			)
+			if err != nil {
+				return "", error(fmt.Errorf("failed to create schema from template: %e", err))
+			}
+			scripts = append(scripts, script)
+		}
 	}
 
 	now := time.Now()
 	stamp := now.Format("2006-01-02 15:04:05")
-	return fmt.Sprintf(banner, actorName, stamp, result), nil
+	return fmt.Sprintf(banner, strings.Join(actorNames, ", "), stamp, strings.Join(scripts, "\n\n")), nil
 }
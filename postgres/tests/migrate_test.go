@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry/postgres"
+)
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := postgres.CreateMigrator(ctx, CONNECTION_STRING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+	t.Cleanup(func() {
+		_, _ = migrator.Down(ctx, postgres.MigrateDownOptions{
+			Steps:       len(postgres.Migrations),
+			DestroyData: true,
+		}, "Migrated")
+	})
+
+	applied, err := migrator.Up(ctx, "Migrated")
+	if err != nil || len(applied) != len(postgres.Migrations) {
+		t.Fatalf("expected every migration to be applied: %+v (%v)", applied, err)
+	}
+	again, err := migrator.Up(ctx, "Migrated")
+	if err != nil || len(again) != 0 {
+		t.Errorf("expected nothing left to apply: %+v (%v)", again, err)
+	}
+
+	statuses, err := migrator.Status(ctx, "Migrated")
+	if err != nil || len(statuses) != len(postgres.Migrations) {
+		t.Fatalf("expected the status of every migration: %+v (%v)", statuses, err)
+	}
+	for _, status := range statuses {
+		if !status.IsApplied() {
+			t.Errorf("expected %s to be applied", status.Name)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, postgres.MigrateDownOptions{Steps: 1}, "Migrated")
+	if err != nil || len(reverted) != 1 || reverted[0].Version != postgres.Migrations[len(postgres.Migrations)-1].Version {
+		t.Fatalf("expected the latest migration to be reverted: %+v (%v)", reverted, err)
+	}
+	statuses, _ = migrator.Status(ctx, "Migrated")
+	if statuses[len(statuses)-1].IsApplied() {
+		t.Error("expected the reverted migration to be pending")
+	}
+
+	// the baseline drops the events, so it isn't reverted by accident
+	reverted, err = migrator.Down(ctx, postgres.MigrateDownOptions{Steps: len(postgres.Migrations)}, "Migrated")
+	if !errors.Is(err, postgres.ErrMigrationDestroysData) || len(reverted) != 0 {
+		t.Errorf("expected reverting the baseline to be refused: %+v (%v)", reverted, err)
+	}
+	statuses, _ = migrator.Status(ctx, "Migrated")
+	if !statuses[0].IsApplied() || !statuses[len(statuses)-2].IsApplied() {
+		t.Error("expected a refused revert to leave every migration applied")
+	}
+}
+
+func TestMigrateNumbersUnversionedEvents(t *testing.T) {
+	ctx := context.Background()
+	migrator, err := postgres.CreateMigrator(ctx, CONNECTION_STRING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+	all := postgres.MigrateDownOptions{Steps: len(postgres.Migrations), DestroyData: true}
+	t.Cleanup(func() {
+		_, _ = migrator.Down(ctx, all, "Legacy")
+	})
+
+	// leave only the original tables, as an earlier release created them
+	_, err = migrator.Up(ctx, "Legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Down(ctx, postgres.MigrateDownOptions{
+		Steps:       len(postgres.Migrations) - 1,
+		DestroyData: true,
+	}, "Legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actorId := uuid.Must(uuid.NewV6())
+	for i := 0; i < 3; i++ {
+		_, err = migrator.Pool.Exec(
+			ctx,
+			"INSERT INTO legacy_events (id, actor_id, content, version) VALUES ($1, $2, '{}', 0)",
+			uuid.Must(uuid.NewV6()),
+			actorId,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = migrator.Up(ctx, "Legacy")
+	if err != nil {
+		t.Fatal("expected the events to be numbered before the index is built", err)
+	}
+	rows, err := migrator.Pool.Query(ctx, "SELECT version, content->>'version' FROM legacy_events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	expected := int64(1)
+	for rows.Next() {
+		var version int64
+		var content string
+		_ = rows.Scan(&version, &content)
+		if version != expected || content != fmt.Sprint(expected) {
+			t.Errorf("expected event %d to be numbered in its stream, got %d (%s)", expected, version, content)
+		}
+		expected++
+	}
+}
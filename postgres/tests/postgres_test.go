@@ -1,9 +1,14 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
 	"github.com/legitbiz/spry/postgres"
 	"github.com/legitbiz/spry/storage"
 	"github.com/legitbiz/spry/tests"
@@ -22,6 +27,7 @@ func TestHandleCommandSuccessfully(t *testing.T) {
 			"player_commands",
 			"player_events",
 			"player_id_map",
+			"player_outbox",
 			"player_snapshots",
 		)
 	})
@@ -91,11 +97,13 @@ func TestAggregateHandlesCommandSuccessfully(t *testing.T) {
 			"vehicle_events",
 			"vehicle_id_map",
 			"vehicle_links",
+			"vehicle_outbox",
 			"vehicle_snapshots",
 			"motorist_commands",
 			"motorist_events",
 			"motorist_id_map",
 			"motorist_links",
+			"motorist_outbox",
 			"motorist_snapshots",
 		)
 	})
@@ -140,3 +148,454 @@ func TestAggregateHandlesCommandSuccessfully(t *testing.T) {
 		t.Error("failed to rehydrate motorist correctly")
 	}
 }
+
+func TestSnapshotsRehydrateTypedActors(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	// enough commands to write snapshots and then handle commands from them
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	for i := 0; i < 30; i++ {
+		results := repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 1})
+		if len(results.Errors) > 0 {
+			t.Fatal(results.Errors)
+		}
+	}
+
+	player, err := repo.Fetch(spry.Identifiers{"name": "Bob"})
+	if err != nil || player.Name != "Bob" || player.HitPoints != 70 {
+		t.Errorf("failed to rehydrate player from snapshot: %+v (%v)", player, err)
+	}
+}
+
+func TestUnitOfWorkRollsBackSharedTransaction(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	unit, err := storage.NewUnitOfWork(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := storage.GetActorRepositoryFor[tests.Player](unit.Storage())
+	results := players.Handle(tests.CreatePlayer{Name: "Bob"})
+	if len(results.Errors) > 0 {
+		t.Fatal(results.Errors)
+	}
+	if err := unit.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	player, _ := storage.GetActorRepositoryFor[tests.Player](store).Fetch(spry.Identifiers{"name": "Bob"})
+	if player.Name != "" {
+		t.Error("expected the unit's writes to be rolled back")
+	}
+}
+
+func TestRenamedPlayerResolvesByAlias(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+		tests.PlayerRenamed{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	results := repo.Handle(tests.RenamePlayer{Name: "Bob", NewName: "Robert"})
+	if len(results.Errors) > 0 {
+		t.Fatal(results.Errors)
+	}
+	repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 10})
+
+	player, err := repo.Fetch(spry.Identifiers{"name": "Robert"})
+	if err != nil || player.Name != "Robert" || player.HitPoints != 90 {
+		t.Errorf("failed to fetch renamed player: %+v (%v)", player, err)
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	uid, _ := store.FetchId(ctx, "Player", spry.Identifiers{"name": "Robert"})
+	history, err := store.FetchIdHistory(ctx, "Player", uid)
+	if err != nil || len(history) != 2 || history[0].IsCurrent() || !history[1].IsCurrent() {
+		t.Errorf("expected the rename in the identifier history: %+v (%v)", history, err)
+	}
+}
+
+func TestRenameToIdentifiersInUseIsRefused(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+		tests.PlayerRenamed{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.CreatePlayer{Name: "Alice"})
+	results := repo.Handle(tests.RenamePlayer{Name: "Alice", NewName: "Bob"})
+	var inUse storage.ErrIdentifiersInUse
+	if len(results.Errors) != 1 || !errors.As(results.Errors[0], &inUse) {
+		t.Fatal("expected the rename to be refused", results.Errors)
+	}
+
+	bob, _ := repo.Fetch(spry.Identifiers{"name": "Bob"})
+	alice, _ := repo.Fetch(spry.Identifiers{"name": "Alice"})
+	if bob.Name != "Bob" || alice.Name != "Alice" {
+		t.Errorf("expected both players to keep their names: %+v %+v", bob, alice)
+	}
+}
+
+func TestDeletedPlayerIsRefused(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.DeletePlayer{Name: "Bob"})
+
+	var deleted storage.ErrActorDeleted
+	_, err := repo.Fetch(spry.Identifiers{"name": "Bob"})
+	if !errors.As(err, &deleted) {
+		t.Errorf("expected the deleted player to fail to fetch: %v", err)
+	}
+	results := repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 1})
+	if len(results.Errors) != 1 || !errors.As(results.Errors[0], &deleted) {
+		t.Errorf("expected the deleted player to refuse commands: %v", results.Errors)
+	}
+
+	// freeing the name lets another player take it
+	repo.Handle(tests.CreatePlayer{Name: "Alice"})
+	repo.Handle(tests.DeletePlayer{Name: "Alice", FreeName: true})
+	created := repo.Handle(tests.CreatePlayer{Name: "Alice"})
+	if len(created.Errors) > 0 || created.Modified.HitPoints != 100 {
+		t.Errorf("expected a new player to take the freed name: %+v", created)
+	}
+}
+
+func TestDeletedKeyRedactsStoredEmails(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterKeyStore(postgres.CreatePostgresKeyStore(store))
+	store.RegisterPrimitives(
+		tests.CustomerRegistered{},
+		tests.OrderPlaced{},
+		tests.RegisterCustomer{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"customer_commands",
+			"customer_events",
+			"customer_id_map",
+			"customer_keys",
+			"customer_outbox",
+			"customer_snapshots",
+		)
+	})
+
+	bob := spry.Identifiers{"number": "001"}
+	repo := storage.GetActorRepositoryFor[tests.Customer](store)
+	repo.Handle(tests.RegisterCustomer{Number: "001", Email: "bob@example.com"})
+	for i := 0; i < 25; i++ {
+		repo.Handle(tests.PlaceOrder{Number: "001", Total: 10})
+	}
+
+	customer, err := repo.Fetch(bob)
+	if err != nil || customer.Email != "bob@example.com" {
+		t.Fatalf("failed to decrypt customer: %+v (%v)", customer, err)
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	uid, _ := store.FetchId(ctx, "Customer", bob)
+	err = store.DeleteKey(ctx, "Customer", uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Commit(ctx)
+
+	customer, err = repo.Fetch(bob)
+	if err != nil || customer.Email != storage.Redacted || customer.Orders != 25 {
+		t.Errorf("expected the email to be redacted: %+v (%v)", customer, err)
+	}
+	history, err := repo.History(bob, storage.HistoryOptions{Limit: 1})
+	if err != nil || len(history) != 1 {
+		t.Fatalf("failed to read the customer's history: %+v (%v)", history, err)
+	}
+	if command, ok := history[0].Command.(tests.RegisterCustomer); !ok || command.Email != storage.Redacted {
+		t.Errorf("expected the command's email to be redacted: %+v", history[0].Command)
+	}
+}
+
+func TestPlayerHistoryIncludesCommands(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+		tests.CreatePlayer{},
+		tests.DamagePlayer{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_keys",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 30})
+
+	history, err := repo.History(spry.Identifiers{"name": "Bob"}, storage.HistoryOptions{})
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected the player's history: %+v (%v)", history, err)
+	}
+	command, ok := history[1].Command.(tests.DamagePlayer)
+	if !ok || command.Damage != 30 {
+		t.Errorf("expected the damage command: %+v", history[1].Command)
+	}
+	if history[0].State.Name != "Bob" || history[1].State.HitPoints != 70 {
+		t.Errorf("expected the player after each event: %+v", history)
+	}
+}
+
+func TestInspectPlayer(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_keys",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 30})
+
+	ctx := context.Background()
+	inspector, err := postgres.CreateInspector(ctx, CONNECTION_STRING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inspector.Close()
+
+	inspection, err := inspector.Inspect(ctx, "Player", spry.Identifiers{"name": "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inspection.Events) != 2 || len(inspection.Commands) != 2 || len(inspection.Links) != 0 {
+		t.Errorf("expected every event and command for the player: %+v", inspection)
+	}
+	_, err = inspector.Inspect(ctx, "Player", spry.Identifiers{"name": "Nobody"})
+	if err == nil {
+		t.Error("expected unknown identifiers to fail")
+	}
+}
+
+func TestExportPlayersToMemory(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_keys",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 30})
+
+	ctx := context.Background()
+	archive := bytes.Buffer{}
+	exported, err := storage.Export(ctx, store, &archive, "Player")
+	if err != nil || exported != 5 {
+		t.Fatal("expected the player's id, commands and events to be exported", exported, err)
+	}
+
+	local := memory.InMemoryStorage()
+	local.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+	_, err = storage.Import(ctx, local, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := storage.GetActorRepositoryFor[tests.Player](local).Fetch(spry.Identifiers{"name": "Bob"})
+	if err != nil || player.HitPoints != 70 {
+		t.Error("expected the player to be reproduced in memory", player, err)
+	}
+
+	// importing into the database it came from stores nothing new
+	_, err = storage.Export(ctx, store, &archive, "Player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = storage.Import(ctx, store, &archive)
+	if err != nil {
+		t.Fatal("expected the import to skip stored records", err)
+	}
+	history, _ := repo.History(spry.Identifiers{"name": "Bob"}, storage.HistoryOptions{})
+	if len(history) != 2 {
+		t.Error("expected the events to be stored once", history)
+	}
+}
+
+func TestRelayDeletesPublishedOutboxRows(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterOutbox(postgres.CreatePostgresOutboxStore(store))
+	store.RegisterPrimitives(
+		tests.PlayerCreated{},
+		tests.PlayerDamaged{},
+	)
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_outbox",
+			"player_snapshots",
+		)
+	})
+
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Bob"})
+	repo.Handle(tests.DamagePlayer{Name: "Bob", Damage: 10})
+
+	publisher := &memory.InMemoryPublisher{}
+	relay := storage.NewRelay(store, publisher, "Player")
+	count, err := relay.Drain(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("expected %d events to be published but got %d (%v)", 2, count, err)
+	}
+	rows, err := CountRows("player_outbox")
+	if err != nil || rows != 0 {
+		t.Errorf("expected published rows to be deleted but %d remain (%v)", rows, err)
+	}
+}
+
+// events are held back while an earlier transaction is open rather than
+// skipped, so the feed waits for the late commit
+func TestFeedWaitsForOpenTransactions(t *testing.T) {
+	store := postgres.CreatePostgresStorage(CONNECTION_STRING)
+	store.RegisterPrimitives(tests.PlayerCreated{})
+
+	t.Cleanup(func() {
+		_ = TruncateTables(
+			"player_commands",
+			"player_events",
+			"player_id_map",
+			"player_snapshots",
+		)
+	})
+
+	ctx := context.Background()
+	late, _ := store.GetContext(ctx)
+	record, _ := storage.NewEventRecord(tests.PlayerCreated{Name: "Bob"})
+	record.ActorName = "Player"
+	record.ActorId, _ = storage.GetId()
+	record.Version = 1
+	if err := store.AddEvents(late, []storage.EventRecord{record}); err != nil {
+		t.Fatal(err)
+	}
+	repo := storage.GetActorRepositoryFor[tests.Player](store)
+	repo.Handle(tests.CreatePlayer{Name: "Alice"})
+
+	read, _ := store.GetContext(ctx)
+	held, err := store.FetchAllEventsSince(read, "Player", uuid.Nil, 0)
+	_ = store.Rollback(read)
+	if err != nil || len(held) != 0 {
+		t.Fatalf("expected events behind the open transaction to be held back but read %d (%v)", len(held), err)
+	}
+
+	if err = store.Commit(late); err != nil {
+		t.Fatal(err)
+	}
+	read, _ = store.GetContext(ctx)
+	defer func() { _ = store.Rollback(read) }()
+	all, err := store.FetchAllEventsSince(read, "Player", uuid.Nil, 0)
+	if err != nil || len(all) != 2 || all[0].Id != record.Id {
+		t.Errorf("expected the held back event to be read first once its transaction ended: %+v (%v)", all, err)
+	}
+}
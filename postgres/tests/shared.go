@@ -28,3 +28,15 @@ func TruncateTables(tableNames ...string) error {
 	}
 	return nil
 }
+
+func CountRows(tableName string) (int, error) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, CONNECTION_STRING)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close(ctx)
+	count := 0
+	err = conn.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s;", tableName)).Scan(&count)
+	return count, err
+}
@@ -64,6 +64,7 @@ func TestActorEventStorage(t *testing.T) {
 	er1.CreatedBy = "Player"
 	er1.CreatedById = aid1
 	er1.Id, _ = storage.GetId()
+	er1.Version = 1
 	er1.Data = e1
 	er1.Type = "PlayerCreated"
 
@@ -74,6 +75,7 @@ func TestActorEventStorage(t *testing.T) {
 	er2.CreatedBy = "Player"
 	er2.CreatedById = aid1
 	er2.Id, _ = storage.GetId()
+	er2.Version = 2
 	er2.Data = e1
 	er2.Type = "PlayerCreated"
 
@@ -129,6 +131,7 @@ func TestAggregateEventStorage(t *testing.T) {
 	er1.CreatedBy = "Motorist"
 	er1.CreatedById = agid
 	er1.Id, _ = storage.GetId()
+	er1.Version = 1
 	er1.Data = e1
 	er1.Type = "VehicleRegistered"
 
@@ -202,6 +205,57 @@ func TestMapStorage(t *testing.T) {
 	}
 }
 
+func TestLinkRemoval(t *testing.T) {
+	store := postgres.CreatePostgresStorage(
+		CONNECTION_STRING,
+	)
+
+	mid, _ := storage.GetId()
+	kept, _ := storage.GetId()
+	sold, _ := storage.GetId()
+
+	ctx, _ := store.GetContext(context.Background())
+	for _, vid := range []uuid.UUID{kept, sold} {
+		err := store.AddLink(ctx, "Motorist", mid, "Vehicle", vid)
+		if err != nil {
+			t.Fatal("failed to add link", err)
+		}
+	}
+	err := store.RemoveLink(ctx, "Motorist", mid, "Vehicle", sold)
+	if err != nil {
+		t.Fatal("failed to remove link", err)
+	}
+	// a removed child can be linked again
+	err = store.AddLink(ctx, "Motorist", mid, "Vehicle", sold)
+	if err != nil {
+		t.Fatal("failed to add link again", err)
+	}
+	err = store.RemoveLink(ctx, "Motorist", mid, "Vehicle", sold)
+	if err != nil {
+		t.Fatal("failed to remove link again", err)
+	}
+
+	idMap, err := store.FetchIdMap(ctx, "Motorist", mid)
+	if err != nil {
+		t.Fatal("failed to read id map", err)
+	}
+	if len(idMap.Aggregated["Vehicle"]) != 1 || idMap.Aggregated["Vehicle"][0] != kept {
+		t.Error("expected only the kept vehicle to be linked", idMap.Aggregated)
+	}
+	if _, ok := idMap.Unlinked["Vehicle"][sold]; !ok {
+		t.Error("expected the sold vehicle to be unlinked", idMap.Unlinked)
+	}
+	if len(idMap.Periods["Vehicle"][sold]) != 2 || len(idMap.Periods["Vehicle"][kept]) != 1 {
+		t.Error("expected a period for each of the vehicles' links", idMap.Periods)
+	}
+
+	tx := storage.GetTx[pgx.Tx](ctx)
+	err = tx.Rollback(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestSnapshotStorage(t *testing.T) {
 	store := postgres.CreatePostgresStorage(
 		CONNECTION_STRING,
@@ -275,3 +329,48 @@ func TestSnapshotStorage(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSupersededSnapshots(t *testing.T) {
+	store := postgres.CreatePostgresStorage(
+		CONNECTION_STRING,
+	)
+
+	uid, _ := storage.GetId()
+	snapshot := storage.Snapshot{
+		Id:            uid,
+		ActorId:       uid,
+		Type:          "Player",
+		CreatedOn:     time.Now(),
+		EventsApplied: 1,
+		LastEventId:   uid,
+		LastCommandId: uid,
+		LastCommandOn: time.Now(),
+		LastEventOn:   time.Now(),
+		Data:          tests.Player{Name: "Billy", HitPoints: 100},
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	err := store.AddSnapshot(ctx, "Player", snapshot, true)
+	if err != nil {
+		t.Fatal("failed to persist snapshot", err)
+	}
+	err = store.SupersedeSnapshots(ctx, "Player", uid)
+	if err != nil {
+		t.Fatal("failed to supersede snapshots", err)
+	}
+
+	latest, err := store.FetchLatestSnapshot(ctx, "Player", uid)
+	if err != nil || latest.IsValid() {
+		t.Error("expected superseded snapshots to be skipped", latest, err)
+	}
+	ids, err := store.FetchActorIds(ctx, "Player")
+	if err != nil {
+		t.Error("failed to list player ids", err)
+	}
+	for _, id := range ids {
+		if id == uid {
+			t.Error("expected only mapped actors to be listed")
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package spry
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/legitbiz/spry/core"
@@ -18,6 +19,14 @@ type HasIdentities interface {
 	GetIdentifierSet() IdentifierSet
 }
 
+// the event types a query consumes keyed by the actor type that
+// produces them; an empty list consumes every event from that actor
+type QuerySources = map[string][]string
+
+type HasSources interface {
+	GetSources() QuerySources
+}
+
 type Actor[T any] interface {
 	HasIdentity
 }
@@ -26,6 +35,23 @@ type Aggregate[T any] interface {
 	HasIdentities
 }
 
+type Query[T any] interface {
+	HasSources
+}
+
+// reacts to events from other actors by issuing commands. Each instance
+// of a process is keyed by the identifiers events are correlated to and
+// has its own state, which events are applied to like any other actor.
+type ProcessManager[T any] interface {
+	HasSources
+	// the identifiers of the process instance an event belongs to,
+	// or nil when the event doesn't concern the process
+	Correlate(event Event) Identifiers
+	// the commands to issue once event has been applied to the
+	// instance's state
+	React(event Event) []Command
+}
+
 type IdSet struct {
 	ids IdentifierSet
 }
@@ -105,10 +131,34 @@ type Event interface {
 	Apply(any) any
 }
 
+// an event that ends an actor's life. Once one is stored the actor
+// can't be fetched and refuses any further commands.
+type Tombstone interface {
+	Event
+	// whether the actor's identifiers can be used by another actor
+	FreesIdentifiers() bool
+}
+
+// a ready made Tombstone for actors that don't need their own
+type ActorDeleted struct {
+	Reason          string
+	FreeIdentifiers bool
+}
+
+func (event ActorDeleted) Apply(actor any) any {
+	return actor
+}
+
+func (event ActorDeleted) FreesIdentifiers() bool {
+	return event.FreeIdentifiers
+}
+
 type Repository[T Actor[T]] interface {
 	Apply(events []Event, actor T) T
 	Fetch(ids Identifiers) (T, error)
+	FetchContext(ctx context.Context, ids Identifiers) (T, error)
 	Handle(command Command) Results[T]
+	HandleContext(ctx context.Context, command Command) Results[T]
 }
 
 func IdentifiersToString(ids Identifiers) (string, error) {
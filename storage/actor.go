@@ -5,6 +5,7 @@ import (
 	"errors"
 	"reflect"
 
+	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
 )
 
@@ -13,15 +14,27 @@ type ActorRepository[T spry.Actor[T]] struct {
 }
 
 func (repository ActorRepository[T]) Fetch(ids spry.Identifiers) (T, error) {
-	ctx := context.Background()
+	return repository.FetchContext(context.Background(), ids)
+}
+
+func (repository ActorRepository[T]) FetchContext(ctx context.Context, ids spry.Identifiers) (T, error) {
 	ctx, err := repository.Storage.GetContext(ctx)
 	if err != nil {
 		return getEmpty[T](), err
 	}
 	snapshot, err := repository.fetchActor(ctx, ids)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return getEmpty[T](), err
+	}
+	// keep any snapshot taken during the read
+	err = repository.commit(ctx)
 	if err != nil {
 		return getEmpty[T](), err
 	}
+	if snapshot.IsDeleted() {
+		return getEmpty[T](), repository.deleted(snapshot)
+	}
 	return snapshot.Data.(T), nil
 }
 
@@ -29,6 +42,7 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 	identifiers := command.(spry.Actor[T]).GetIdentifiers()
 	baseline, err := repository.fetchActor(ctx, identifiers)
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Errors: []error{err},
 		}
@@ -36,25 +50,73 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 
 	cmdRecord, s, done := repository.createCommandRecord(command, baseline)
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	actor := baseline.Data.(T)
+	if baseline.IsDeleted() {
+		return repository.storeCommand(ctx, cmdRecord, baseline, identifiers, spry.Results[T]{
+			Original: actor,
+			Errors:   []error{repository.deleted(baseline)},
+		})
+	}
 	events, errors := command.Handle(actor)
+
+	if len(errors) > 0 {
+		return repository.storeCommand(ctx, cmdRecord, baseline, identifiers, spry.Results[T]{
+			Original: actor,
+			Errors:   errors,
+		})
+	}
+	if len(events) == 0 {
+		return repository.storeCommand(ctx, cmdRecord, baseline, identifiers, spry.Results[T]{
+			Original: actor,
+			Modified: actor,
+			Errors:   errors,
+		})
+	}
+
 	next := repository.Apply(events, actor)
 	eventRecords, s, done := repository.createEventRecords(events, baseline, cmdRecord, IdAssignments{})
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	snapshot, s, done := repository.createSnapshot(next, baseline, cmdRecord, eventRecords)
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	// store id map
 	err = repository.Storage.AddMap(ctx, repository.ActorName, identifiers, snapshot.ActorId)
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+			Errors:   []error{err},
+		}
+	}
+
+	// follow the actor if the events changed its identifiers
+	err = repository.rekey(ctx, baseline, next)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+			Errors:   []error{err},
+		}
+	}
+
+	err = repository.retireIds(ctx, snapshot.ActorId, eventRecords)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Original: actor,
 			Modified: next,
@@ -75,6 +137,18 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 		}
 	}
 
+	// store the command alongside the events it produced
+	err = repository.Storage.AddCommand(ctx, repository.ActorName, cmdRecord)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+			Errors:   []error{err},
+		}
+	}
+
 	config := spry.GetActorMeta[T]()
 	// do we allow snapshotting during read?
 	// if so, have we passed the event threshold?
@@ -88,6 +162,7 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 			config.SnapshotDuringPartition,
 		)
 		if err != nil {
+			_ = repository.Storage.Rollback(ctx)
 			return spry.Results[T]{
 				Original: actor,
 				Modified: next,
@@ -97,9 +172,8 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 		}
 	}
 
-	err = repository.Storage.Commit(ctx)
+	err = repository.commit(ctx)
 	if err != nil {
-		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Original: actor,
 			Modified: next,
@@ -107,6 +181,7 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 			Errors:   []error{err},
 		}
 	}
+	repository.Storage.Committed(ctx, eventRecords)
 
 	return spry.Results[T]{
 		Original: actor,
@@ -116,6 +191,38 @@ func (repository ActorRepository[T]) handleActorCommand(ctx context.Context, com
 	}
 }
 
+// identifiers come from the actor's state, so when events change them
+// the id map has to follow. New actors are mapped by the command instead.
+func (repository ActorRepository[T]) rekey(ctx context.Context, baseline Snapshot, next T) error {
+	if baseline.EventsApplied == 0 {
+		return nil
+	}
+	before, err := spry.IdentifiersToString(baseline.Data.(T).GetIdentifiers())
+	if err != nil {
+		return err
+	}
+	ids := next.GetIdentifiers()
+	after, err := spry.IdentifiersToString(ids)
+	if err != nil {
+		return err
+	}
+	if before == after {
+		return nil
+	}
+	retire := spry.GetActorMeta[T]().IdentifierPolicy == spry.RetireIdentifiers
+	return repository.Storage.Rekey(ctx, repository.ActorName, baseline.ActorId, ids, retire)
+}
+
+// a tombstone can free the actor's identifiers for another actor
+func (repository ActorRepository[T]) retireIds(ctx context.Context, actorId uuid.UUID, records []EventRecord) error {
+	for _, record := range records {
+		if tombstone, ok := record.Data.(spry.Tombstone); ok && tombstone.FreesIdentifiers() {
+			return repository.Storage.RetireIds(ctx, repository.ActorName, actorId)
+		}
+	}
+	return nil
+}
+
 func (repository ActorRepository[T]) createSnapshot(next T, baseline Snapshot, cmdRecord CommandRecord, events []EventRecord) (Snapshot, spry.Results[T], bool) {
 	lastEventRecord := events[len(events)-1]
 	snapshot, err := NewSnapshot(next)
@@ -126,27 +233,43 @@ func (repository ActorRepository[T]) createSnapshot(next T, baseline Snapshot, c
 		}, true
 	}
 	snapshot.ActorId = baseline.ActorId
+	snapshot.LastEventMap = baseline.LastEventMap
 	snapshot.LastCommandId = cmdRecord.Id
 	snapshot.LastCommandOn = cmdRecord.HandledOn
 	snapshot.LastEventId = lastEventRecord.Id
 	snapshot.LastEventOn = lastEventRecord.CreatedOn
-	snapshot.EventsApplied += uint64(len(events))
-	snapshot.EventSinceSnapshot += len(events)
-	snapshot.Version++
+	snapshot.EventsApplied = baseline.EventsApplied + uint64(len(events))
+	snapshot.EventSinceSnapshot = baseline.EventSinceSnapshot + len(events)
+	snapshot.Version = lastEventRecord.Version
+	snapshot.DeletedOn = baseline.DeletedOn
+	for _, record := range events {
+		if _, ok := record.Data.(spry.Tombstone); ok {
+			snapshot.DeletedOn = record.CreatedOn
+		}
+	}
 	return snapshot, spry.Results[T]{}, false
 }
 
 func (repository ActorRepository[T]) Handle(command spry.Command) spry.Results[T] {
-	ctx, err := repository.Storage.GetContext(context.Background())
-	if err != nil {
-		return spry.Results[T]{Errors: []error{err}}
-	}
-	if _, ok := command.(spry.Actor[T]); ok {
-		return repository.handleActorCommand(ctx, command)
-	}
-	return spry.Results[T]{
-		Errors: []error{errors.New("command must implement GetIdentifiers")},
+	return repository.HandleContext(context.Background(), command)
+}
+
+func (repository ActorRepository[T]) HandleContext(ctx context.Context, command spry.Command) spry.Results[T] {
+	if _, ok := command.(spry.Actor[T]); !ok {
+		return spry.Results[T]{
+			Errors: []error{errors.New("command must implement GetIdentifiers")},
+		}
 	}
+	return withRetries(ctx, spry.GetActorMeta[T](), func() spry.Results[T] {
+		if err := ctx.Err(); err != nil {
+			return spry.Results[T]{Errors: []error{err}}
+		}
+		txCtx, err := repository.Storage.GetContext(ctx)
+		if err != nil {
+			return spry.Results[T]{Errors: []error{err}}
+		}
+		return repository.handleActorCommand(txCtx, command)
+	})
 }
 
 func GetActorRepositoryFor[T spry.Actor[T]](storage Storage) ActorRepository[T] {
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 
 	"github.com/gofrs/uuid"
@@ -14,7 +15,10 @@ type AggregateRepository[T spry.Aggregate[T]] struct {
 }
 
 func (repository AggregateRepository[T]) Fetch(ids spry.Identifiers) (T, error) {
-	ctx := context.Background()
+	return repository.FetchContext(context.Background(), ids)
+}
+
+func (repository AggregateRepository[T]) FetchContext(ctx context.Context, ids spry.Identifiers) (T, error) {
 	ctx, err := repository.Storage.GetContext(ctx)
 	if err != nil {
 		return getEmpty[T](), err
@@ -25,26 +29,45 @@ func (repository AggregateRepository[T]) Fetch(ids spry.Identifiers) (T, error)
 	}
 	assignments, err := repository.getAssignedIds(ctx, identifiers)
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return getEmpty[T](), err
 	}
 	snapshot, err := repository.fetchAggregate(ctx, assignments)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return getEmpty[T](), err
+	}
+	// keep any id assignments or snapshot made during the read
+	err = repository.commit(ctx)
 	if err != nil {
 		return getEmpty[T](), err
 	}
+	if snapshot.IsDeleted() {
+		return getEmpty[T](), repository.deleted(snapshot)
+	}
 	return snapshot.Data.(T), nil
 }
 
 func (repository AggregateRepository[T]) Handle(command spry.Command) spry.Results[T] {
-	ctx, err := repository.Storage.GetContext(context.Background())
-	if err != nil {
-		return spry.Results[T]{Errors: []error{err}}
-	}
-	if _, ok := command.(spry.Aggregate[T]); ok {
-		return repository.handleAggregateCommand(ctx, command)
-	}
-	return spry.Results[T]{
-		Errors: []error{errors.New("command must implement GetIdentifierSet")},
+	return repository.HandleContext(context.Background(), command)
+}
+
+func (repository AggregateRepository[T]) HandleContext(ctx context.Context, command spry.Command) spry.Results[T] {
+	if _, ok := command.(spry.Aggregate[T]); !ok {
+		return spry.Results[T]{
+			Errors: []error{errors.New("command must implement GetIdentifierSet")},
+		}
 	}
+	return withRetries(ctx, spry.GetActorMeta[T](), func() spry.Results[T] {
+		if err := ctx.Err(); err != nil {
+			return spry.Results[T]{Errors: []error{err}}
+		}
+		txCtx, err := repository.Storage.GetContext(ctx)
+		if err != nil {
+			return spry.Results[T]{Errors: []error{err}}
+		}
+		return repository.handleAggregateCommand(txCtx, command)
+	})
 }
 
 func (repository AggregateRepository[T]) createSnapshot(next T, baseline Snapshot, cmdRecord CommandRecord, events []EventRecord) (Snapshot, spry.Results[T], bool) {
@@ -57,17 +80,25 @@ func (repository AggregateRepository[T]) createSnapshot(next T, baseline Snapsho
 		}, true
 	}
 	snapshot.ActorId = baseline.ActorId
+	snapshot.LastEventMap = baseline.LastEventMap
 	snapshot.LastCommandId = cmdRecord.Id
 	snapshot.LastCommandOn = cmdRecord.HandledOn
 	snapshot.LastEventId = lastEventRecord.Id
 	snapshot.LastEventOn = lastEventRecord.CreatedOn
-	snapshot.EventsApplied += uint64(len(events))
-	snapshot.EventSinceSnapshot += len(events)
-	snapshot.Version++
+	snapshot.EventsApplied = baseline.EventsApplied + uint64(len(events))
+	snapshot.EventSinceSnapshot = baseline.EventSinceSnapshot + len(events)
+	snapshot.Version = baseline.Version
+	snapshot.DeletedOn = baseline.DeletedOn
 
 	for _, er := range events {
-		if er.ActorName != repository.ActorName {
+		if er.ActorId == snapshot.ActorId {
+			snapshot.Version = er.Version
+			if _, ok := er.Data.(spry.Tombstone); ok {
+				snapshot.DeletedOn = er.CreatedOn
+			}
+		} else {
 			snapshot.AddLastEventFor(er.ActorName, er.ActorId, er.Id)
+			snapshot.AddLastVersionFor(er.ActorName, er.ActorId, er.Version)
 		}
 	}
 
@@ -113,6 +144,7 @@ func (repository Repository[T]) getAggregatedEventsSince(ctx context.Context, ag
 
 	snapshot.UpdateFromMap(idMap)
 
+	// stores leave out what unlinked children did after their link ended
 	records, err := repository.Storage.FetchAggregatedEventsSince(
 		ctx,
 		snapshot.Type,
@@ -139,49 +171,69 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 
 	assignments, err := repository.getAssignedIds(ctx, identifiers)
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Errors: []error{err},
 		}
 	}
 
 	baseline, err := repository.fetchAggregate(ctx, assignments)
+	if err == nil {
+		err = repository.syncChildVersions(ctx, &baseline, assignments)
+	}
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Errors: []error{err},
 		}
 	}
 
-	// fetch events for associated records
-
 	cmdRecord, s, done := repository.createCommandRecord(command, baseline)
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	actor := baseline.Data.(T)
+	if baseline.IsDeleted() {
+		return repository.storeCommand(ctx, cmdRecord, baseline, aggregateId, spry.Results[T]{
+			Original: actor,
+			Errors:   []error{repository.deleted(baseline)},
+		})
+	}
 	events, errors := command.Handle(actor)
 
 	if len(errors) > 0 {
-		return spry.Results[T]{
+		return repository.storeCommand(ctx, cmdRecord, baseline, aggregateId, spry.Results[T]{
 			Original: actor,
 			Errors:   errors,
-		}
+		})
+	}
+	if len(events) == 0 {
+		return repository.storeCommand(ctx, cmdRecord, baseline, aggregateId, spry.Results[T]{
+			Original: actor,
+			Modified: actor,
+			Errors:   errors,
+		})
 	}
 
 	next := repository.Apply(events, actor)
 	eventRecords, s, done := repository.createEventRecords(events, baseline, cmdRecord, assignments)
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	snapshot, s, done := repository.createSnapshot(next, baseline, cmdRecord, eventRecords)
 	if done {
+		_ = repository.Storage.Rollback(ctx)
 		return s
 	}
 
 	// store id map
 	err = repository.Storage.AddMap(ctx, repository.ActorName, aggregateId, snapshot.ActorId)
 	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Original: actor,
 			Modified: next,
@@ -202,6 +254,30 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 		}
 	}
 
+	// end the links of any children the events removed
+	err = repository.removeLinks(ctx, snapshot.ActorId, events, assignments)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+			Errors:   []error{err},
+		}
+	}
+
+	// store the command alongside the events it produced
+	err = repository.Storage.AddCommand(ctx, repository.ActorName, cmdRecord)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+			Errors:   []error{err},
+		}
+	}
+
 	config := spry.GetActorMeta[T]()
 	// do we allow snapshotting during read?
 	// if so, have we passed the event threshold?
@@ -215,6 +291,7 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 			config.SnapshotDuringPartition,
 		)
 		if err != nil {
+			_ = repository.Storage.Rollback(ctx)
 			return spry.Results[T]{
 				Original: actor,
 				Modified: next,
@@ -224,9 +301,8 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 		}
 	}
 
-	err = repository.Storage.Commit(ctx)
+	err = repository.commit(ctx)
 	if err != nil {
-		_ = repository.Storage.Rollback(ctx)
 		return spry.Results[T]{
 			Original: actor,
 			Modified: next,
@@ -234,6 +310,7 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 			Errors:   []error{err},
 		}
 	}
+	repository.Storage.Committed(ctx, eventRecords)
 
 	return spry.Results[T]{
 		Original: actor,
@@ -243,6 +320,59 @@ func (repository AggregateRepository[T]) handleAggregateCommand(ctx context.Cont
 	}
 }
 
+// a child's events from outside its links aren't applied to the aggregate,
+// but the next event written to one of the command's children still has
+// to follow them
+func (repository AggregateRepository[T]) syncChildVersions(ctx context.Context, baseline *Snapshot, assignments IdAssignments) error {
+	for childId, assignment := range assignments.byId {
+		if assignment.ActorName == repository.ActorName {
+			continue
+		}
+		records, err := repository.Storage.FetchEventsSince(
+			ctx,
+			assignment.ActorName,
+			childId,
+			baseline.LastEvents[assignment.ActorName][childId],
+		)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			last := records[len(records)-1]
+			if last.Version > baseline.GetLastVersionFor(assignment.ActorName, childId) {
+				baseline.AddLastVersionFor(assignment.ActorName, childId, last.Version)
+			}
+		}
+	}
+	return nil
+}
+
+func (repository AggregateRepository[T]) removeLinks(
+	ctx context.Context,
+	aggregateId uuid.UUID,
+	events []spry.Event,
+	assignments IdAssignments) error {
+	for _, event := range events {
+		child := spry.GetEventMeta(event).Unlinks
+		if child == "" {
+			continue
+		}
+		aggregated, ok := event.(spry.Aggregate[T])
+		if !ok {
+			return fmt.Errorf("%s must implement GetIdentifierSet to unlink %s", reflect.TypeOf(event).Name(), child)
+		}
+		idSet := spry.IdSetFromIdentifierSet(aggregated.GetIdentifierSet())
+		for _, ids := range idSet.GetIdsFor(child) {
+			childId := assignments.GetIdFor(child, ids)
+			err := repository.Storage.RemoveLink(ctx, repository.ActorName, aggregateId, child, childId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func GetAggregateRepositoryFor[T spry.Aggregate[T]](storage Storage) AggregateRepository[T] {
 	actorType := reflect.TypeOf(*new(T))
 	actorName := actorType.Name()
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// the kinds of record an archive holds, in the order they're exported
+const (
+	ArchivedId       = "id"
+	ArchivedLink     = "link"
+	ArchivedCommand  = "command"
+	ArchivedEvent    = "event"
+	ArchivedSnapshot = "snapshot"
+)
+
+// the number of records Import stores in each transaction
+var ImportBatchSize = 500
+
+// one line of an archive. Record holds an IdRecord, a LinkRecord,
+// a CommandRecord, an EventRecord or a Snapshot depending on Kind.
+type ArchiveRecord struct {
+	Kind      string          `json:"kind"`
+	ActorName string          `json:"actor"`
+	Record    json.RawMessage `json:"record"`
+}
+
+// a set of identifiers that has mapped to an actor
+type IdRecord struct {
+	Id      uuid.UUID `json:"id"`
+	ActorId uuid.UUID `json:"actorId"`
+	IdentifierRecord
+}
+
+// a child that is or was linked to an aggregate
+type LinkRecord struct {
+	Id         uuid.UUID `json:"id"`
+	ParentType string    `json:"parentType"`
+	ParentId   uuid.UUID `json:"parentId"`
+	ChildType  string    `json:"childType"`
+	ChildId    uuid.UUID `json:"childId"`
+	StartingOn time.Time `json:"startingOn"`
+	// zero while the link is active
+	EndingOn time.Time `json:"endingOn"`
+}
+
+func (link LinkRecord) IsActive() bool {
+	return link.EndingOn.IsZero()
+}
+
+// reads and writes every record of an actor type as it's stored, for
+// backups and for copying data between environments or backends
+type ArchiveStore interface {
+	// passes the id maps, links, commands, events and current snapshots
+	// of the actor type to write, in that order
+	Export(context.Context, string, func(ArchiveRecord) error) error
+	// stores the record unless it's already stored
+	Import(context.Context, ArchiveRecord, TypeMap) error
+}
+
+// writes every record stored for each actor type to w as newline-delimited
+// JSON and returns the number written. Personal data is written encrypted
+// and keys aren't exported, so it reads as Redacted wherever they're missing.
+func Export(ctx context.Context, storage Storage, w io.Writer, actorNames ...string) (int, error) {
+	ctx, err := storage.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = storage.Rollback(ctx) }()
+	encoder := json.NewEncoder(w)
+	count := 0
+	for _, actorName := range actorNames {
+		err = storage.ExportRecords(ctx, actorName, func(record ArchiveRecord) error {
+			count++
+			return encoder.Encode(record)
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// stores the records Export wrote to r, skipping the ones already stored,
+// so the same archive can be imported more than once. Returns the number
+// of records read.
+func Import(ctx context.Context, storage Storage, r io.Reader) (int, error) {
+	decoder := json.NewDecoder(r)
+	count := 0
+	for {
+		txCtx, err := storage.GetContext(ctx)
+		if err != nil {
+			return count, err
+		}
+		read := 0
+		for ; read < ImportBatchSize; read++ {
+			record := ArchiveRecord{}
+			err = decoder.Decode(&record)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err == nil {
+				err = storage.ImportRecord(txCtx, record)
+			}
+			if err != nil {
+				_ = storage.Rollback(txCtx)
+				return count, err
+			}
+		}
+		err = storage.Commit(txCtx)
+		if err != nil {
+			return count, err
+		}
+		count += read
+		if read < ImportBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func (storage Stores[Tx]) ExportRecords(ctx context.Context, actorName string, write func(ArchiveRecord) error) error {
+	if storage.Archive == nil {
+		return ErrArchiveMissing
+	}
+	return storage.Archive.Export(ctx, actorName, write)
+}
+
+func (storage Stores[Tx]) ImportRecord(ctx context.Context, record ArchiveRecord) error {
+	if storage.Archive == nil {
+		return ErrArchiveMissing
+	}
+	return storage.Archive.Import(ctx, record, storage.Primitives)
+}
+
+func NewArchiveRecord(kind string, actorName string, record any) (ArchiveRecord, error) {
+	data, err := spry.ToJson(record)
+	if err != nil {
+		return ArchiveRecord{}, err
+	}
+	return ArchiveRecord{Kind: kind, ActorName: actorName, Record: data}, nil
+}
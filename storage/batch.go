@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/legitbiz/spry"
+)
+
+// adds err to every result in the batch
+func failBatch[T any](batch []spry.Results[T], err error) []spry.Results[T] {
+	for i := range batch {
+		batch[i].Errors = append(batch[i].Errors, err)
+	}
+	return batch
+}
+
+func (repository ActorRepository[T]) abortBatch(ctx context.Context, batch []spry.Results[T], err error) []spry.Results[T] {
+	_ = repository.Storage.Rollback(ctx)
+	return failBatch(batch, err)
+}
+
+// the command that aborted a batch is still logged, in a transaction of
+// its own, against the actor as it was before the batch. Inside a
+// UnitOfWork the abort fails the unit, so nothing is logged.
+func (repository ActorRepository[T]) logRejected(
+	ctx context.Context,
+	cmdRecord CommandRecord,
+	baseline Snapshot,
+	ids spry.Identifiers,
+	errs []error) error {
+	txCtx, err := repository.Storage.GetContext(ctx)
+	if errors.Is(err, ErrUnitOfWorkFailed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cmdRecord.HandledVersion = baseline.Version
+	cmdRecord = unhandled(cmdRecord, baseline, ids)
+	cmdRecord.SetErrors(errs)
+	err = repository.Storage.AddCommand(txCtx, repository.ActorName, cmdRecord)
+	if err != nil {
+		_ = repository.Storage.Rollback(txCtx)
+		return err
+	}
+	return repository.commit(txCtx)
+}
+
+// every command in a batch must target the actor the batch is for;
+// the errors are reported against the commands that don't
+func (repository ActorRepository[T]) checkBatchTargets(ids spry.Identifiers, commands []spry.Command) ([]spry.Results[T], bool) {
+	batch := make([]spry.Results[T], len(commands))
+	key, err := spry.IdentifiersToString(ids)
+	if err != nil {
+		return failBatch(batch, err), false
+	}
+	valid := true
+	for i, command := range commands {
+		actor, ok := command.(spry.Actor[T])
+		if !ok {
+			batch[i].Errors = []error{errors.New("command must implement GetIdentifiers")}
+			valid = false
+			continue
+		}
+		targets := actor.GetIdentifiers()
+		if target, _ := spry.IdentifiersToString(targets); target != key {
+			batch[i].Errors = []error{ErrCommandTargetsOtherActor{
+				ActorName:   repository.ActorName,
+				Identifiers: ids,
+				Targets:     targets,
+			}}
+			valid = false
+		}
+	}
+	if !valid {
+		return failBatch(batch, ErrBatchAborted), false
+	}
+	return batch, true
+}
+
+// handles each command against the state left by the command before it
+// and stores everything they produced in a single transaction
+func (repository ActorRepository[T]) handleActorCommands(
+	ctx context.Context,
+	ids spry.Identifiers,
+	commands []spry.Command) []spry.Results[T] {
+	batch := make([]spry.Results[T], len(commands))
+	config := spry.GetActorMeta[T]()
+
+	current, err := repository.fetchActor(ctx, ids)
+	if err != nil {
+		return repository.abortBatch(ctx, batch, err)
+	}
+
+	baseline := current
+	cmdRecords := []CommandRecord{}
+	eventRecords := []EventRecord{}
+	for i, command := range commands {
+		cmdRecord, s, done := repository.createCommandRecord(command, current)
+		if done {
+			return repository.abortBatch(ctx, batch, s.Errors[0])
+		}
+
+		actor := current.Data.(T)
+		var events []spry.Event
+		var errors []error
+		// commands after a tombstone are refused like any rejection
+		if current.IsDeleted() {
+			errors = []error{repository.deleted(current)}
+		} else {
+			events, errors = command.Handle(actor)
+		}
+
+		if len(errors) > 0 {
+			batch[i] = spry.Results[T]{
+				Original: actor,
+				Errors:   errors,
+			}
+			if config.BatchPolicy == spry.AbortBatch {
+				batch = repository.abortBatch(ctx, batch, ErrBatchAborted)
+				err = repository.logRejected(ctx, cmdRecord, baseline, ids, errors)
+				if err != nil {
+					batch[i].Errors = append(batch[i].Errors, err)
+				}
+				return batch
+			}
+			cmdRecord.SetErrors(errors)
+			cmdRecords = append(cmdRecords, cmdRecord)
+			continue
+		}
+		if len(events) == 0 {
+			batch[i] = spry.Results[T]{
+				Original: actor,
+				Modified: actor,
+			}
+			cmdRecords = append(cmdRecords, cmdRecord)
+			continue
+		}
+
+		next := repository.Apply(events, actor)
+		records, s, done := repository.createEventRecords(events, current, cmdRecord, IdAssignments{})
+		if done {
+			return repository.abortBatch(ctx, batch, s.Errors[0])
+		}
+		current, s, done = repository.createSnapshot(next, current, cmdRecord, records)
+		if done {
+			return repository.abortBatch(ctx, batch, s.Errors[0])
+		}
+
+		batch[i] = spry.Results[T]{
+			Original: actor,
+			Modified: next,
+			Events:   events,
+		}
+		cmdRecords = append(cmdRecords, cmdRecord)
+		eventRecords = append(eventRecords, records...)
+	}
+
+	if len(eventRecords) > 0 {
+		// store id map
+		err = repository.Storage.AddMap(ctx, repository.ActorName, ids, current.ActorId)
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+
+		err = repository.rekey(ctx, baseline, current.Data.(T))
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+
+		err = repository.retireIds(ctx, current.ActorId, eventRecords)
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+
+		// store events
+		err = repository.Storage.AddEvents(ctx, eventRecords)
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+	}
+
+	for _, cmdRecord := range cmdRecords {
+		if len(eventRecords) == 0 {
+			cmdRecord = unhandled(cmdRecord, baseline, ids)
+		}
+		err = repository.Storage.AddCommand(ctx, repository.ActorName, cmdRecord)
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+	}
+
+	// only the state at the end of the batch is worth a snapshot
+	if len(eventRecords) > 0 &&
+		config.SnapshotDuringWrite &&
+		current.EventSinceSnapshot >= config.SnapshotFrequency {
+		current.EventSinceSnapshot = 0
+		err = repository.Storage.AddSnapshot(
+			ctx,
+			repository.ActorName,
+			current,
+			config.SnapshotDuringPartition,
+		)
+		if err != nil {
+			return repository.abortBatch(ctx, batch, err)
+		}
+	}
+
+	err = repository.commit(ctx)
+	if err != nil {
+		return failBatch(batch, err)
+	}
+	repository.Storage.Committed(ctx, eventRecords)
+	return batch
+}
+
+// handles a sequence of commands against the actor identified by ids,
+// loading it once and committing once. Returns one result per command.
+// Nothing is handled when a command targets another actor. How a rejected
+// command affects the batch is set by ActorMeta.BatchPolicy.
+func (repository ActorRepository[T]) HandleAll(ids spry.Identifiers, commands ...spry.Command) []spry.Results[T] {
+	return repository.HandleAllContext(context.Background(), ids, commands...)
+}
+
+func (repository ActorRepository[T]) HandleAllContext(
+	ctx context.Context,
+	ids spry.Identifiers,
+	commands ...spry.Command) []spry.Results[T] {
+	if len(commands) == 0 {
+		return []spry.Results[T]{}
+	}
+	if batch, ok := repository.checkBatchTargets(ids, commands); !ok {
+		return batch
+	}
+	return withBatchRetries(ctx, spry.GetActorMeta[T](), func() []spry.Results[T] {
+		if err := ctx.Err(); err != nil {
+			return failBatch(make([]spry.Results[T], len(commands)), err)
+		}
+		txCtx, err := repository.Storage.GetContext(ctx)
+		if err != nil {
+			return failBatch(make([]spry.Results[T], len(commands)), err)
+		}
+		return repository.handleActorCommands(txCtx, ids, commands)
+	})
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/legitbiz/spry"
+)
+
+// handles a command with a repository created from storage and
+// returns the errors from handling it
+type route = func(context.Context, Storage, spry.Command) []error
+
+// sends commands to the repository of the actor type that handles them
+type Dispatcher struct {
+	routes map[string]route
+}
+
+// handles each command with the repository created from storage for
+// the actor type it was routed to
+func (dispatcher Dispatcher) Dispatch(ctx context.Context, storage Storage, command spry.Command) ([]error, error) {
+	name := reflect.TypeOf(command).Name()
+	handle, ok := dispatcher.routes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoRoute, name)
+	}
+	return handle(ctx, storage, command), nil
+}
+
+func NewDispatcher() Dispatcher {
+	return Dispatcher{routes: map[string]route{}}
+}
+
+// routes each type of command given to T's actor repository
+func RouteToActor[T spry.Actor[T]](dispatcher Dispatcher, commands ...spry.Command) {
+	for _, command := range commands {
+		dispatcher.routes[reflect.TypeOf(command).Name()] = func(ctx context.Context, storage Storage, command spry.Command) []error {
+			return GetActorRepositoryFor[T](storage).HandleContext(ctx, command).Errors
+		}
+	}
+}
+
+// routes each type of command given to T's aggregate repository
+func RouteToAggregate[T spry.Aggregate[T]](dispatcher Dispatcher, commands ...spry.Command) {
+	for _, command := range commands {
+		dispatcher.routes[reflect.TypeOf(command).Name()] = func(ctx context.Context, storage Storage, command spry.Command) []error {
+			return GetAggregateRepositoryFor[T](storage).HandleContext(ctx, command).Errors
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// returned when another writer appended events to an actor's stream
+// after the baseline used to handle a command was loaded
+type ErrConcurrencyConflict struct {
+	ActorName string
+	ActorId   uuid.UUID
+	Version   uint64
+}
+
+func (err ErrConcurrencyConflict) Error() string {
+	return fmt.Sprintf(
+		"concurrency conflict: %s %s already has an event at version %d",
+		err.ActorName,
+		err.ActorId,
+		err.Version,
+	)
+}
+
+// returned when fetching or handling commands for an actor after a
+// spry.Tombstone event ended its life
+type ErrActorDeleted struct {
+	ActorName string
+	ActorId   uuid.UUID
+	DeletedOn time.Time
+}
+
+func (err ErrActorDeleted) Error() string {
+	return fmt.Sprintf(
+		"%s %s was deleted on %s",
+		err.ActorName,
+		err.ActorId,
+		err.DeletedOn.Format(time.RFC3339),
+	)
+}
+
+// returned when an actor is re-keyed to identifiers another actor
+// of the same type currently holds
+type ErrIdentifiersInUse struct {
+	ActorName   string
+	Identifiers spry.Identifiers
+}
+
+func (err ErrIdentifiersInUse) Error() string {
+	return fmt.Sprintf(
+		"%s identifiers %v are in use by another actor",
+		err.ActorName,
+		err.Identifiers,
+	)
+}
+
+// returned for a HandleAll command whose identifiers aren't the batch's
+type ErrCommandTargetsOtherActor struct {
+	ActorName   string
+	Identifiers spry.Identifiers
+	Targets     spry.Identifiers
+}
+
+func (err ErrCommandTargetsOtherActor) Error() string {
+	return fmt.Sprintf(
+		"%s command targets %v but the batch is for %v",
+		err.ActorName,
+		err.Targets,
+		err.Identifiers,
+	)
+}
+
+// added to every result of a HandleAll batch that was not stored
+// because one of its commands was rejected
+var ErrBatchAborted = errors.New("batch aborted: a command in the batch was rejected")
+
+// returned by a UnitOfWork after one of its repositories rolled back
+var ErrUnitOfWorkFailed = errors.New("unit of work was rolled back by one of its repositories")
+
+// returned by a UnitOfWork that was already committed or rolled back
+var ErrUnitOfWorkClosed = errors.New("unit of work has already been committed or rolled back")
+
+// returned when deleting a key from a Storage with no KeyStore
+var ErrKeyStoreMissing = errors.New("no KeyStore has been registered")
+
+// returned when exporting or importing with a Storage that has no ArchiveStore
+var ErrArchiveMissing = errors.New("no ArchiveStore has been set")
+
+// returned by a process manager when a command it issued in reaction to
+// an event was rejected; the event is processed again on the next pass
+type ErrReactionRejected struct {
+	ProcessName string
+	EventId     uuid.UUID
+	Command     spry.Command
+	Errors      []error
+}
+
+func (err ErrReactionRejected) Error() string {
+	return fmt.Sprintf(
+		"%s's %s reacting to event %s was rejected: %v",
+		err.ProcessName,
+		reflect.TypeOf(err.Command).Name(),
+		err.EventId,
+		err.Errors,
+	)
+}
+
+// returned by a Dispatcher for a command type with no route
+var ErrNoRoute = errors.New("no repository is routed to handle command")
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// how many events a subscription reads from the store at a time
+var FeedPageSize = 100
+
+// how long a subscription waits before checking for new events when the
+// event store can't signal that events were added
+var FeedPollInterval = time.Second
+
+// event stores that can signal when events are added for an actor type
+// let subscriptions wait for a signal instead of polling
+type EventNotifier interface {
+	Notify(context.Context, string) (<-chan struct{}, error)
+}
+
+// streams every event of an actor type committed after eventId, in the
+// order they were committed, until ctx is cancelled. The id of the last
+// record received is the checkpoint to resume from.
+//
+// An event is only delivered once every transaction that might commit
+// an event before it has finished. With Postgres that means no feed gets
+// past a transaction that has written anything and is still open, even
+// one that never touches events, so a long or idle writer anywhere in the
+// database stalls every subscription (and process manager) until it
+// ends. Keep transactions short where feeds are used.
+func (storage Stores[Tx]) Subscribe(ctx context.Context, actorName string, eventId uuid.UUID) (<-chan EventRecord, <-chan error) {
+	records := make(chan EventRecord)
+	errs := make(chan error, 1)
+	go storage.feed(ctx, actorName, eventId, records, errs)
+	return records, errs
+}
+
+func (storage Stores[Tx]) feed(ctx context.Context, actorName string, eventId uuid.UUID, records chan<- EventRecord, errs chan<- error) {
+	defer close(records)
+	defer close(errs)
+
+	var wake <-chan struct{}
+	if notifier, ok := storage.Events.(EventNotifier); ok {
+		signal, err := notifier.Notify(ctx, actorName)
+		if err != nil {
+			errs <- err
+			return
+		}
+		wake = signal
+	}
+
+	last := eventId
+	for {
+		page, err := storage.fetchPage(ctx, actorName, last)
+		if err != nil {
+			if ctx.Err() == nil {
+				errs <- err
+			}
+			return
+		}
+		for _, record := range page {
+			select {
+			case records <- record:
+				last = record.Id
+			case <-ctx.Done():
+				return
+			}
+		}
+		// a full page means there are likely more events waiting
+		if len(page) == FeedPageSize {
+			continue
+		}
+		// stores may hold back events committed behind a transaction that
+		// is still open, so a signal isn't the only reason to read again
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-time.After(FeedPollInterval):
+		}
+	}
+}
+
+func (storage Stores[Tx]) fetchPage(ctx context.Context, actorName string, eventId uuid.UUID) ([]EventRecord, error) {
+	txCtx, err := storage.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// pages are only ever read
+	defer func() { _ = storage.Rollback(txCtx) }()
+	return storage.FetchAllEventsSince(txCtx, actorName, eventId, FeedPageSize)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/legitbiz/spry"
+)
+
+// identifies the shape of an actor's state so that snapshots written
+// for an older shape are not reused. An explicit ActorMeta.SchemaVersion
+// takes the place of the shape when an actor provides one.
+func GetFingerprint(actor any) string {
+	if hasMeta, ok := actor.(spry.HasMeta); ok {
+		if version := hasMeta.GetActorMeta().SchemaVersion; version > 0 {
+			return fmt.Sprintf("v%d", version)
+		}
+	}
+	builder := strings.Builder{}
+	describeType(&builder, reflect.TypeOf(actor), map[reflect.Type]bool{})
+	hash := sha256.Sum256([]byte(builder.String()))
+	return hex.EncodeToString(hash[:8])
+}
+
+func describeType(builder *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	if t == nil {
+		builder.WriteString("nil")
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		// recursive types only need to be described once
+		if seen[t] {
+			builder.WriteString(t.String())
+			return
+		}
+		seen[t] = true
+		builder.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			builder.WriteString(field.Name)
+			builder.WriteString(" ")
+			describeType(builder, field.Type, seen)
+			builder.WriteString(" ")
+			builder.WriteString(string(field.Tag))
+			builder.WriteString(";")
+		}
+		builder.WriteString("}")
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		builder.WriteString(t.Kind().String())
+		builder.WriteString(" ")
+		describeType(builder, t.Elem(), seen)
+	case reflect.Map:
+		builder.WriteString("map[")
+		describeType(builder, t.Key(), seen)
+		builder.WriteString("]")
+		describeType(builder, t.Elem(), seen)
+	default:
+		builder.WriteString(t.String())
+	}
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// narrows and pages the entries History returns
+type HistoryOptions struct {
+	// only events of these types are returned, all of them when empty
+	EventTypes []string
+	// the number of matching entries to skip
+	Offset int
+	// the most entries to return, no limit when 0
+	Limit int
+}
+
+func (options HistoryOptions) includes(eventType string) bool {
+	if len(options.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range options.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (options HistoryOptions) page(entries []int) []int {
+	if options.Offset >= len(entries) {
+		return []int{}
+	}
+	entries = entries[options.Offset:]
+	if options.Limit > 0 && options.Limit < len(entries) {
+		entries = entries[:options.Limit]
+	}
+	return entries
+}
+
+// an event in an actor's past along with the command that caused it.
+// Entries leave out the ids stores use internally.
+type HistoryEntry[T any] struct {
+	// the type name of the event
+	Type string
+	// the event's payload
+	Event spry.Event
+	// the type of the actor that created the event
+	CreatedBy string
+	// when the event was created
+	CreatedOn time.Time
+	// the type name of the command that caused the event
+	InitiatedBy string
+	// the command's payload, nil when the command log has no record of it
+	Command any
+	// the actor as it was once the event was applied
+	State T
+}
+
+// replays every event fetchEvents finds from an empty actor, keeping the
+// state after each one. Reading history never writes snapshots.
+func (repository Repository[T]) fetchHistory(
+	ctx context.Context,
+	actorId uuid.UUID,
+	fetchEvents func(context.Context, Snapshot) ([]spry.Event, []EventRecord, error),
+	options HistoryOptions) ([]HistoryEntry[T], error) {
+	if actorId == uuid.Nil {
+		return []HistoryEntry[T]{}, nil
+	}
+	baseline, err := NewSnapshot(getEmpty[T]())
+	if err != nil {
+		return nil, err
+	}
+	baseline.ActorId = actorId
+	_, records, err := fetchEvents(ctx, baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []int{}
+	for i, record := range records {
+		if options.includes(record.Type) {
+			matched = append(matched, i)
+		}
+	}
+	matched = options.page(matched)
+	if len(matched) == 0 {
+		return []HistoryEntry[T]{}, nil
+	}
+
+	commands, err := repository.fetchInitiators(ctx, records, matched)
+	if err != nil {
+		return nil, err
+	}
+
+	// events past the last entry returned can't change its state
+	last := matched[len(matched)-1]
+	states := make([]T, last+1)
+	state := getEmpty[T]()
+	for i, record := range records[:last+1] {
+		state = repository.Apply([]spry.Event{record.Data.(spry.Event)}, state)
+		// later events may change what the state shares with earlier ones
+		states[i] = copyValue(reflect.ValueOf(&state).Elem()).Interface().(T)
+	}
+
+	entries := make([]HistoryEntry[T], len(matched))
+	for i, index := range matched {
+		record := records[index]
+		entries[i] = HistoryEntry[T]{
+			Type:        record.Type,
+			Event:       record.Data.(spry.Event),
+			CreatedBy:   record.CreatedBy,
+			CreatedOn:   record.CreatedOn,
+			InitiatedBy: record.InitiatedBy,
+			Command:     commands[record.InitiatedById],
+			State:       states[index],
+		}
+	}
+	return entries, nil
+}
+
+// copies v along with the pointers, slices and maps it holds so that
+// nothing in the copy is shared. Unexported fields are copied as is.
+func copyValue(v reflect.Value) reflect.Value {
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				out.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(copyValue(v.Elem()))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t).Elem()
+		out.Set(copyValue(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return out
+	}
+	return v
+}
+
+// the payloads of the commands behind the records at indexes. Commands
+// are logged by the type of actor that handled them, which is the type
+// each event was created by.
+func (repository Repository[T]) fetchInitiators(ctx context.Context, records []EventRecord, indexes []int) (map[uuid.UUID]any, error) {
+	byHandler := map[string][]uuid.UUID{}
+	for _, index := range indexes {
+		record := records[index]
+		if record.InitiatedById == uuid.Nil {
+			continue
+		}
+		handler := record.CreatedBy
+		if handler == "" {
+			handler = record.ActorName
+		}
+		byHandler[handler] = append(byHandler[handler], record.InitiatedById)
+	}
+
+	commands := map[uuid.UUID]any{}
+	for handler, commandIds := range byHandler {
+		found, err := repository.Storage.FetchCommands(ctx, handler, commandIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, command := range found {
+			commands[command.Id] = command.Data
+		}
+	}
+	return commands, nil
+}
+
+// the events recorded for the actor identified by ids, oldest first
+func (repository ActorRepository[T]) History(ids spry.Identifiers, options HistoryOptions) ([]HistoryEntry[T], error) {
+	return repository.HistoryContext(context.Background(), ids, options)
+}
+
+func (repository ActorRepository[T]) HistoryContext(ctx context.Context, ids spry.Identifiers, options HistoryOptions) ([]HistoryEntry[T], error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) ([]HistoryEntry[T], error) {
+		return repository.fetchHistory(ctx, actorId, repository.actorEvents, options)
+	})
+}
+
+// the events recorded for the aggregate identified by ids and the
+// children linked to it, oldest first
+func (repository AggregateRepository[T]) History(ids spry.Identifiers, options HistoryOptions) ([]HistoryEntry[T], error) {
+	return repository.HistoryContext(context.Background(), ids, options)
+}
+
+func (repository AggregateRepository[T]) HistoryContext(ctx context.Context, ids spry.Identifiers, options HistoryOptions) ([]HistoryEntry[T], error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) ([]HistoryEntry[T], error) {
+		return repository.fetchHistory(ctx, actorId, repository.aggregateEvents, options)
+	})
+}
@@ -1,16 +1,48 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
 )
 
 type AggregatedIds = map[string][]uuid.UUID
 
+// a set of identifiers an actor has been known by
+type IdentifierRecord struct {
+	Identifiers spry.Identifiers `json:"identifiers"`
+	StartingOn  time.Time        `json:"startingOn"`
+	// zero while these are the actor's current identifiers
+	EndingOn time.Time `json:"endingOn"`
+	// retired identifiers no longer resolve to the actor
+	Retired bool `json:"retired"`
+}
+
+func (record IdentifierRecord) IsCurrent() bool {
+	return record.EndingOn.IsZero()
+}
+
+// a span of time a child was linked to an aggregate
+type LinkPeriod struct {
+	StartingOn time.Time
+	// zero while the link is active
+	EndingOn time.Time
+}
+
+func (period LinkPeriod) Contains(on time.Time) bool {
+	return !on.Before(period.StartingOn) &&
+		(period.EndingOn.IsZero() || !on.After(period.EndingOn))
+}
+
 type AggregateIdMap struct {
 	ActorName  string
 	ActorId    uuid.UUID
 	Aggregated AggregatedIds
+	// children whose links to the aggregate have ended and when
+	Unlinked map[string]map[uuid.UUID]time.Time
+	// every period each child has been linked to the aggregate
+	Periods map[string]map[uuid.UUID][]LinkPeriod
 }
 
 func (idMap *AggregateIdMap) AddIdsFor(child string, id ...uuid.UUID) {
@@ -22,11 +54,40 @@ func (idMap *AggregateIdMap) AddIdsFor(child string, id ...uuid.UUID) {
 	}
 }
 
+// records one of the periods a child was linked; a child can be linked
+// more than once, so the latest end is kept as when it was unlinked
+func (idMap *AggregateIdMap) AddLinkPeriod(child string, id uuid.UUID, startingOn time.Time, endingOn time.Time) {
+	if idMap.Periods == nil {
+		idMap.Periods = map[string]map[uuid.UUID][]LinkPeriod{}
+	}
+	if idMap.Periods[child] == nil {
+		idMap.Periods[child] = map[uuid.UUID][]LinkPeriod{}
+	}
+	idMap.Periods[child][id] = append(idMap.Periods[child][id], LinkPeriod{
+		StartingOn: startingOn,
+		EndingOn:   endingOn,
+	})
+	if endingOn.IsZero() {
+		return
+	}
+	if idMap.Unlinked == nil {
+		idMap.Unlinked = map[string]map[uuid.UUID]time.Time{}
+	}
+	if idMap.Unlinked[child] == nil {
+		idMap.Unlinked[child] = map[uuid.UUID]time.Time{}
+	}
+	if endingOn.After(idMap.Unlinked[child][id]) {
+		idMap.Unlinked[child][id] = endingOn
+	}
+}
+
 func CreateAggregateIdMap(actorName string, actorId uuid.UUID) AggregateIdMap {
 	return AggregateIdMap{
 		ActorName:  actorName,
 		ActorId:    actorId,
 		Aggregated: AggregatedIds{},
+		Unlinked:   map[string]map[uuid.UUID]time.Time{},
+		Periods:    map[string]map[uuid.UUID][]LinkPeriod{},
 	}
 }
 
@@ -35,7 +96,12 @@ func EmptyAggregateIdMap() AggregateIdMap {
 }
 
 type LastEventMap struct {
-	LastEvents map[string]map[uuid.UUID]uuid.UUID
+	LastEvents   map[string]map[uuid.UUID]uuid.UUID
+	LastVersions map[string]map[uuid.UUID]uint64
+	// the periods each child was linked; only the events a child created
+	// during one of them belong to the aggregate. Set from the id map
+	// each time the aggregate is read, so it's never stored.
+	LinkPeriods map[string]map[uuid.UUID][]LinkPeriod `json:"-"`
 }
 
 func (last *LastEventMap) AddLastEventFor(child string, childId uuid.UUID, lastEventId uuid.UUID) {
@@ -48,8 +114,66 @@ func (last *LastEventMap) AddLastEventFor(child string, childId uuid.UUID, lastE
 	}
 }
 
+func (last *LastEventMap) AddLastVersionFor(child string, childId uuid.UUID, version uint64) {
+	if last.LastVersions == nil {
+		last.LastVersions = map[string]map[uuid.UUID]uint64{}
+	}
+	versions := last.LastVersions
+	if m, ok := versions[child]; ok {
+		m[childId] = version
+	} else {
+		versions[child] = map[uuid.UUID]uint64{}
+		versions[child][childId] = version
+	}
+}
+
+func (last *LastEventMap) GetLastVersionFor(child string, childId uuid.UUID) uint64 {
+	if m, ok := last.LastVersions[child]; ok {
+		return m[childId]
+	}
+	return 0
+}
+
+// the periods the child was linked to the aggregate, none when
+// its links weren't read from the id map
+func (last *LastEventMap) GetLinkPeriodsFor(child string, childId uuid.UUID) []LinkPeriod {
+	return last.LinkPeriods[child][childId]
+}
+
+// whether an event the child created on the given time belongs to the
+// aggregate; a child without link periods contributes all of its events
+func (last *LastEventMap) IsLinkedOn(child string, childId uuid.UUID, on time.Time) bool {
+	periods := last.GetLinkPeriodsFor(child, childId)
+	if len(periods) == 0 {
+		return true
+	}
+	for _, period := range periods {
+		if period.Contains(on) {
+			return true
+		}
+	}
+	return false
+}
+
 func (last *LastEventMap) UpdateFromMap(idMap AggregateIdMap) {
 	events := last.LastEvents
+	last.LinkPeriods = map[string]map[uuid.UUID][]LinkPeriod{}
+	// unlinked children still contribute the events from their link
+	// periods when the aggregate is rebuilt
+	for k, linked := range idMap.Periods {
+		for id, periods := range linked {
+			if _, ok := events[k]; !ok {
+				events[k] = map[uuid.UUID]uuid.UUID{}
+			}
+			if _, ok := events[k][id]; !ok {
+				events[k][id] = uuid.Nil
+			}
+			if _, ok := last.LinkPeriods[k]; !ok {
+				last.LinkPeriods[k] = map[uuid.UUID][]LinkPeriod{}
+			}
+			last.LinkPeriods[k][id] = periods
+		}
+	}
 	for k, list := range idMap.Aggregated {
 		if _, ok := events[k]; !ok {
 			events[k] = map[uuid.UUID]uuid.UUID{}
@@ -68,7 +192,8 @@ func (last *LastEventMap) UpdateFromMap(idMap AggregateIdMap) {
 
 func CreateLastEvents() LastEventMap {
 	return LastEventMap{
-		LastEvents: map[string]map[uuid.UUID]uuid.UUID{},
+		LastEvents:   map[string]map[uuid.UUID]uuid.UUID{},
+		LastVersions: map[string]map[uuid.UUID]uint64{},
 	}
 }
 
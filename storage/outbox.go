@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// an event waiting to be published outside of the application;
+// the event's Id is the key consumers should dedupe on since
+// delivery is at-least-once
+type OutboxRecord struct {
+	Event EventRecord
+	// how many times publishing has failed
+	Attempts int
+	// the earliest time the relay should try to publish again
+	NextAttemptOn time.Time
+}
+
+// holds the OutboxStore registered with RegisterOutbox; events are only
+// copied to an outbox once one is registered
+type Outbox struct {
+	lock  sync.RWMutex
+	store OutboxStore
+}
+
+func (outbox *Outbox) getStore() OutboxStore {
+	if outbox == nil {
+		return nil
+	}
+	outbox.lock.RLock()
+	defer outbox.lock.RUnlock()
+	return outbox.store
+}
+
+func (outbox *Outbox) SetStore(store OutboxStore) {
+	outbox.lock.Lock()
+	defer outbox.lock.Unlock()
+	outbox.store = store
+}
+
+type Publisher interface {
+	Publish(context.Context, EventRecord) error
+}
+
+// drains the outbox of each actor type through a Publisher
+type Relay struct {
+	Storage    Storage
+	Publisher  Publisher
+	ActorNames []string
+	// how many records to publish per actor type in each pass
+	BatchSize int
+	// how long to wait between passes that found nothing to publish
+	PollInterval time.Duration
+	// how long to wait before the first retry of a failed publish,
+	// doubled for each failure after it up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+// publishes everything in the outbox that is due and returns the number
+// of records published. Records are only removed from the outbox once the
+// Publisher accepts them, so a crash can cause duplicates but not losses.
+func (relay Relay) Drain(ctx context.Context) (int, error) {
+	published := 0
+	for _, actorName := range relay.ActorNames {
+		count, err := relay.drainActor(ctx, actorName)
+		published += count
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// drains the outbox repeatedly until ctx is cancelled
+func (relay Relay) Run(ctx context.Context) error {
+	for {
+		published, err := relay.Drain(ctx)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if published > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(relay.PollInterval):
+		}
+	}
+}
+
+func (relay Relay) drainActor(ctx context.Context, actorName string) (int, error) {
+	txCtx, err := relay.Storage.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := relay.Storage.FetchOutbox(txCtx, actorName, relay.BatchSize)
+	if err != nil {
+		_ = relay.Storage.Rollback(txCtx)
+		return 0, err
+	}
+
+	published := 0
+	for _, record := range pending {
+		if err = relay.Publisher.Publish(ctx, record.Event); err != nil {
+			err = relay.Storage.MarkUnpublished(
+				txCtx,
+				actorName,
+				record.Event.Id,
+				time.Now().Add(retryDelay(relay.retryConfig(), record.Attempts+1)),
+			)
+		} else {
+			err = relay.Storage.MarkPublished(txCtx, actorName, record.Event.Id)
+			published++
+		}
+		if err != nil {
+			_ = relay.Storage.Rollback(txCtx)
+			return 0, err
+		}
+	}
+
+	err = relay.Storage.Commit(txCtx)
+	if err != nil {
+		_ = relay.Storage.Rollback(txCtx)
+		return 0, err
+	}
+	return published, nil
+}
+
+// failed publishes back off the same way failed commands do
+func (relay Relay) retryConfig() spry.ActorMeta {
+	return spry.ActorMeta{
+		RetryBackoff: relay.RetryBackoff,
+		MaxBackoff:   relay.MaxBackoff,
+	}
+}
+
+func NewRelay(storage Storage, publisher Publisher, actorNames ...string) Relay {
+	return Relay{
+		Storage:      storage,
+		Publisher:    publisher,
+		ActorNames:   actorNames,
+		BatchSize:    100,
+		PollInterval: time.Second,
+		RetryBackoff: time.Second,
+		MaxBackoff:   time.Minute,
+	}
+}
+
+// writes each event as a line of JSON, e.g. to os.Stdout or a file
+type WriterPublisher struct {
+	lock   sync.Mutex
+	Writer io.Writer
+}
+
+func (publisher *WriterPublisher) Publish(ctx context.Context, record EventRecord) error {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	return json.NewEncoder(publisher.Writer).Encode(record)
+}
+
+func NewWriterPublisher(writer io.Writer) *WriterPublisher {
+	return &WriterPublisher{Writer: writer}
+}
+
+func (storage Stores[Tx]) FetchOutbox(ctx context.Context, actorName string, limit int) ([]OutboxRecord, error) {
+	outbox := storage.Outbox.getStore()
+	if outbox == nil {
+		return []OutboxRecord{}, nil
+	}
+	pending, err := outbox.FetchPending(ctx, actorName, limit, storage.Primitives)
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range pending {
+		pending[i].Event.Data, err = storage.Shredder.Decrypt(ctx, record.Event.ActorName, record.Event.ActorId, record.Event.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// published records are removed from the outbox
+func (storage Stores[Tx]) MarkPublished(ctx context.Context, actorName string, eventId uuid.UUID) error {
+	outbox := storage.Outbox.getStore()
+	if outbox == nil {
+		return nil
+	}
+	return outbox.MarkPublished(ctx, actorName, eventId)
+}
+
+func (storage Stores[Tx]) MarkUnpublished(ctx context.Context, actorName string, eventId uuid.UUID, nextAttempt time.Time) error {
+	outbox := storage.Outbox.getStore()
+	if outbox == nil {
+		return nil
+	}
+	return outbox.MarkUnpublished(ctx, actorName, eventId, nextAttempt)
+}
@@ -0,0 +1,382 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// what a field tagged `spry:"pii"` reads as once its actor's key is deleted
+const Redacted = "[redacted]"
+
+const piiTag = "pii"
+const ciphertextPrefix = "spry:pii:"
+
+// holds the key each actor's personal data is encrypted with. Deleting
+// an actor's key erases its personal data from every event and snapshot
+// without rewriting them.
+type KeyStore interface {
+	Add(context.Context, string, uuid.UUID, []byte) error
+	Delete(context.Context, string, uuid.UUID) error
+	// returns nil when the actor has no key
+	Fetch(context.Context, string, uuid.UUID) ([]byte, error)
+}
+
+// encrypts the string fields tagged `spry:"pii"` in command, event and
+// snapshot payloads before they're stored and decrypts them when they're read
+type Shredder struct {
+	lock sync.RWMutex
+	keys KeyStore
+}
+
+func (shredder *Shredder) getKeys() KeyStore {
+	if shredder == nil {
+		return nil
+	}
+	shredder.lock.RLock()
+	defer shredder.lock.RUnlock()
+	return shredder.keys
+}
+
+func (shredder *Shredder) SetKeys(keys KeyStore) {
+	shredder.lock.Lock()
+	defer shredder.lock.Unlock()
+	shredder.keys = keys
+}
+
+func (shredder *Shredder) getOrCreateKey(ctx context.Context, keys KeyStore, actorName string, actorId uuid.UUID) ([]byte, error) {
+	key, err := keys.Fetch(ctx, actorName, actorId)
+	if err != nil || key != nil {
+		return key, err
+	}
+	key = make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	err = keys.Add(ctx, actorName, actorId, key)
+	if err != nil {
+		return nil, err
+	}
+	// another writer may have added a key first
+	return keys.Fetch(ctx, actorName, actorId)
+}
+
+// returns a copy of data with its personal data encrypted using the
+// actor's key, creating the key if the actor doesn't have one yet
+func (shredder *Shredder) Encrypt(ctx context.Context, actorName string, actorId uuid.UUID, data any) (any, error) {
+	keys := shredder.getKeys()
+	if keys == nil || data == nil || !hasPII(reflect.TypeOf(data)) {
+		return data, nil
+	}
+	key, err := shredder.getOrCreateKey(ctx, keys, actorName, actorId)
+	if err != nil {
+		return data, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return data, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return data, err
+	}
+	encrypted, err := transformStrings(reflect.ValueOf(data), false, func(plain string) (string, error) {
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+		return ciphertextPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+	})
+	if err != nil {
+		return data, err
+	}
+	return encrypted.Interface(), nil
+}
+
+// returns a copy of data with its personal data decrypted. Anything that
+// can't be decrypted, because the key was deleted, reads as Redacted.
+func (shredder *Shredder) Decrypt(ctx context.Context, actorName string, actorId uuid.UUID, data any) (any, error) {
+	keys := shredder.getKeys()
+	if keys == nil || data == nil || !hasCiphertext(reflect.ValueOf(data)) {
+		return data, nil
+	}
+	key, err := keys.Fetch(ctx, actorName, actorId)
+	if err != nil {
+		return data, err
+	}
+	var gcm cipher.AEAD
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return data, err
+		}
+		gcm, err = cipher.NewGCM(block)
+		if err != nil {
+			return data, err
+		}
+	}
+	decrypted, err := transformStrings(reflect.ValueOf(data), true, func(value string) (string, error) {
+		if !strings.HasPrefix(value, ciphertextPrefix) {
+			return value, nil
+		}
+		if gcm == nil {
+			return Redacted, nil
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ciphertextPrefix))
+		if err != nil || len(sealed) < gcm.NonceSize() {
+			return Redacted, nil
+		}
+		nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		plain, err := gcm.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			// written with a key that has since been replaced
+			return Redacted, nil
+		}
+		return string(plain), nil
+	})
+	if err != nil {
+		return data, err
+	}
+	return decrypted.Interface(), nil
+}
+
+func (shredder *Shredder) encryptRecords(ctx context.Context, records []EventRecord) ([]EventRecord, error) {
+	if shredder.getKeys() == nil {
+		return records, nil
+	}
+	encrypted := make([]EventRecord, len(records))
+	for i, record := range records {
+		data, err := shredder.Encrypt(ctx, record.ActorName, record.ActorId, record.Data)
+		if err != nil {
+			return nil, err
+		}
+		record.Data = data
+		encrypted[i] = record
+	}
+	return encrypted, nil
+}
+
+func (shredder *Shredder) decryptRecords(ctx context.Context, records []EventRecord) ([]EventRecord, error) {
+	if shredder.getKeys() == nil {
+		return records, nil
+	}
+	// stores may hand back the records they hold, so those are left alone
+	decrypted := make([]EventRecord, len(records))
+	for i, record := range records {
+		data, err := shredder.Decrypt(ctx, record.ActorName, record.ActorId, record.Data)
+		if err != nil {
+			return nil, err
+		}
+		record.Data = data
+		decrypted[i] = record
+	}
+	return decrypted, nil
+}
+
+// commands that never reached an actor have no key to be encrypted
+// with, so their personal data isn't kept at all
+func (shredder *Shredder) encryptCommand(ctx context.Context, actorName string, command CommandRecord) (CommandRecord, error) {
+	if shredder.getKeys() == nil || command.Data == nil || !hasPII(reflect.TypeOf(command.Data)) {
+		return command, nil
+	}
+	if command.HandledBy == uuid.Nil {
+		redacted, err := transformStrings(reflect.ValueOf(command.Data), false, func(string) (string, error) {
+			return Redacted, nil
+		})
+		if err != nil {
+			return command, err
+		}
+		command.Data = redacted.Interface()
+		return command, nil
+	}
+	data, err := shredder.Encrypt(ctx, actorName, command.HandledBy, command.Data)
+	if err != nil {
+		return command, err
+	}
+	command.Data = data
+	return command, nil
+}
+
+func (shredder *Shredder) decryptCommands(ctx context.Context, actorName string, commands []CommandRecord) ([]CommandRecord, error) {
+	if shredder.getKeys() == nil {
+		return commands, nil
+	}
+	decrypted := make([]CommandRecord, len(commands))
+	for i, command := range commands {
+		data, err := shredder.Decrypt(ctx, actorName, command.HandledBy, command.Data)
+		if err != nil {
+			return nil, err
+		}
+		command.Data = data
+		decrypted[i] = command
+	}
+	return decrypted, nil
+}
+
+var piiTypes sync.Map
+
+// whether any string field in t is tagged as personal data
+func hasPII(t reflect.Type) bool {
+	if known, ok := piiTypes.Load(t); ok {
+		return known.(bool)
+	}
+	found := findPII(t, map[reflect.Type]bool{})
+	piiTypes.Store(t, found)
+	return found
+}
+
+func findPII(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findPII(t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("spry") == piiTag || findPII(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasCiphertext(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.HasPrefix(v.String(), ciphertextPrefix)
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && hasCiphertext(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && hasCiphertext(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasCiphertext(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasCiphertext(iter.Value()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copies v, passing each string inside a field tagged as personal data
+// through fn (or every string when all is set). Containers are copied
+// so that the stored value is never modified.
+func transformStrings(v reflect.Value, all bool, fn func(string) (string, error)) (reflect.Value, error) {
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.String:
+		if !all {
+			return v, nil
+		}
+		value, err := fn(v.String())
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(t).Elem()
+		out.SetString(value)
+		return out, nil
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tagged := all || field.Tag.Get("spry") == piiTag
+			value, err := transformStrings(v.Field(i), tagged, fn)
+			if err != nil {
+				return v, err
+			}
+			out.Field(i).Set(value)
+		}
+		return out, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+		value, err := transformStrings(v.Elem(), all, fn)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(value)
+		return out, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		value, err := transformStrings(v.Elem(), all, fn)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(t).Elem()
+		out.Set(value)
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, err := transformStrings(v.Index(i), all, fn)
+			if err != nil {
+				return v, err
+			}
+			out.Index(i).Set(value)
+		}
+		return out, nil
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			value, err := transformStrings(v.Index(i), all, fn)
+			if err != nil {
+				return v, err
+			}
+			out.Index(i).Set(value)
+		}
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := transformStrings(iter.Value(), all, fn)
+			if err != nil {
+				return v, err
+			}
+			out.SetMapIndex(iter.Key(), value)
+		}
+		return out, nil
+	}
+	return v, nil
+}
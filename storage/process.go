@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// keeps the state of a process manager's instances and dispatches the
+// commands they issue. Reacting to a page of events, handling the commands
+// that resulted and recording how far the process has read all happen in
+// one UnitOfWork, so a crash never loses an event or reacts to one twice.
+// Only one ProcessRepository should run for each process type at a time.
+type ProcessRepository[T spry.ProcessManager[T]] struct {
+	Repository[T]
+	Dispatcher Dispatcher
+}
+
+// the checkpoints for each source are kept with a snapshot of their own
+func (repository ProcessRepository[T]) getIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"process": repository.ActorName}
+}
+
+// the state of the process instance that events are correlated to by ids
+func (repository ProcessRepository[T]) Fetch(ids spry.Identifiers) (T, error) {
+	return repository.FetchContext(context.Background(), ids)
+}
+
+func (repository ProcessRepository[T]) FetchContext(ctx context.Context, ids spry.Identifiers) (T, error) {
+	ctx, err := repository.Storage.GetContext(ctx)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	// instances only change while processing
+	defer func() { _ = repository.Storage.Rollback(ctx) }()
+	snapshot, _, err := repository.getLatestSnapshot(ctx, ids)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	return snapshot.Data.(T), nil
+}
+
+// reacts to every source event added since the last call and returns
+// the number of events read
+func (repository ProcessRepository[T]) Process(ctx context.Context) (int, error) {
+	read := 0
+	for {
+		count, err := repository.processPage(ctx)
+		read += count
+		if err != nil || count == 0 {
+			return read, err
+		}
+	}
+}
+
+// processes events repeatedly until ctx is cancelled
+func (repository ProcessRepository[T]) Run(ctx context.Context) error {
+	for {
+		read, err := repository.Process(ctx)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if read > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(FeedPollInterval):
+		}
+	}
+}
+
+func (repository ProcessRepository[T]) processPage(ctx context.Context) (int, error) {
+	unit, err := NewUnitOfWork(ctx, repository.Storage)
+	if err != nil {
+		return 0, err
+	}
+	// everything below reads and writes through the unit
+	scoped := repository.Repository
+	scoped.Storage = unit.Storage()
+	txCtx, err := scoped.Storage.GetContext(ctx)
+	if err != nil {
+		_ = unit.Rollback()
+		return 0, err
+	}
+
+	checkpoint, _, err := scoped.getLatestSnapshot(txCtx, repository.getIdentifiers())
+	if err != nil {
+		_ = unit.Rollback()
+		return 0, err
+	}
+	records, err := repository.getSourceEventsSince(txCtx, scoped.Storage, &checkpoint)
+	if err != nil || len(records) == 0 {
+		_ = unit.Rollback()
+		return 0, err
+	}
+
+	instances, err := repository.react(txCtx, scoped, unit.Storage(), records)
+	if err != nil {
+		_ = unit.Rollback()
+		return 0, err
+	}
+
+	for _, instance := range instances {
+		err = scoped.Storage.AddMap(txCtx, repository.ActorName, instance.ids, instance.ActorId)
+		if err != nil {
+			_ = unit.Rollback()
+			return 0, err
+		}
+		err = scoped.addSnapshot(txCtx, instance.Snapshot)
+		if err != nil {
+			_ = unit.Rollback()
+			return 0, err
+		}
+	}
+
+	last := records[len(records)-1]
+	checkpoint.EventsApplied += uint64(len(records))
+	checkpoint.LastEventId = last.Id
+	checkpoint.LastEventOn = last.CreatedOn
+	checkpoint.Version++
+	err = scoped.Storage.AddMap(txCtx, repository.ActorName, repository.getIdentifiers(), checkpoint.ActorId)
+	if err != nil {
+		_ = unit.Rollback()
+		return 0, err
+	}
+	err = scoped.addSnapshot(txCtx, checkpoint)
+	if err != nil {
+		_ = unit.Rollback()
+		return 0, err
+	}
+
+	err = unit.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+type processInstance struct {
+	Snapshot
+	ids spry.Identifiers
+}
+
+// applies each event to the instance it correlates to and dispatches the
+// commands the instance issues in reaction. A rejected command fails the
+// page so its events are processed again, unless the process manager's
+// BatchPolicy is SkipRejected; then it's only kept in the command log.
+func (repository ProcessRepository[T]) react(
+	ctx context.Context,
+	scoped Repository[T],
+	storage Storage,
+	records []EventRecord) (map[string]*processInstance, error) {
+	empty := getEmpty[T]()
+	sources := empty.GetSources()
+	config := spry.GetActorMeta[T]()
+	instances := map[string]*processInstance{}
+	for _, record := range records {
+		if !consumes(sources[record.ActorName], record.Type) {
+			continue
+		}
+		event := record.Data.(spry.Event)
+		ids := empty.Correlate(event)
+		if len(ids) == 0 {
+			continue
+		}
+		key, err := spry.IdentifiersToString(ids)
+		if err != nil {
+			return nil, err
+		}
+		instance, ok := instances[key]
+		if !ok {
+			snapshot, _, err := scoped.getLatestSnapshot(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			instance = &processInstance{Snapshot: snapshot, ids: ids}
+			instances[key] = instance
+		}
+
+		state := scoped.Apply([]spry.Event{event}, instance.Data.(T))
+		instance.Data = state
+		instance.EventsApplied++
+		instance.LastEventId = record.Id
+		instance.LastEventOn = record.CreatedOn
+		instance.Version++
+
+		for _, command := range state.React(event) {
+			errs, err := repository.Dispatcher.Dispatch(ctx, storage, command)
+			if err != nil {
+				return nil, err
+			}
+			if len(errs) > 0 && config.BatchPolicy == spry.AbortBatch {
+				return nil, ErrReactionRejected{
+					ProcessName: repository.ActorName,
+					EventId:     record.Id,
+					Command:     command,
+					Errors:      errs,
+				}
+			}
+		}
+	}
+	return instances, nil
+}
+
+// reads a page of events from each source and moves the checkpoints past
+// the events returned. Events are only returned up to the point every
+// source has been read to, so that they're always handled in the order
+// they were committed.
+func (repository ProcessRepository[T]) getSourceEventsSince(ctx context.Context, storage Storage, snapshot *Snapshot) ([]EventRecord, error) {
+	if snapshot.Checkpoints == nil {
+		snapshot.Checkpoints = map[string]uuid.UUID{}
+	}
+
+	pages := map[string][]EventRecord{}
+	var horizon *EventPosition
+	for actorName := range getEmpty[T]().GetSources() {
+		page, err := storage.FetchAllEventsSince(
+			ctx,
+			actorName,
+			snapshot.Checkpoints[actorName],
+			FeedPageSize,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pages[actorName] = page
+		// a full page may stop short of events in the other sources
+		if len(page) == FeedPageSize {
+			last := page[len(page)-1].Position
+			if horizon == nil || last.Before(*horizon) {
+				horizon = &last
+			}
+		}
+	}
+
+	records := []EventRecord{}
+	for actorName, page := range pages {
+		for _, record := range page {
+			if horizon != nil && horizon.Before(record.Position) {
+				break
+			}
+			records = append(records, record)
+			snapshot.Checkpoints[actorName] = record.Id
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Position.Before(records[j].Position)
+	})
+	return records, nil
+}
+
+func GetProcessRepositoryFor[T spry.ProcessManager[T]](storage Storage, dispatcher Dispatcher) ProcessRepository[T] {
+	actorType := reflect.TypeOf(*new(T))
+	actorName := actorType.Name()
+	return ProcessRepository[T]{
+		Repository: Repository[T]{
+			ActorType: actorType,
+			ActorName: actorName,
+			Storage:   storage,
+		},
+		Dispatcher: dispatcher,
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// a read-only model folded from the event streams of other actors;
+// queries have no event stream of their own, only snapshots
+type QueryRepository[T spry.Query[T]] struct {
+	Repository[T]
+}
+
+func (repository QueryRepository[T]) Fetch() (T, error) {
+	return repository.FetchContext(context.Background())
+}
+
+func (repository QueryRepository[T]) FetchContext(ctx context.Context) (T, error) {
+	ctx, err := repository.Storage.GetContext(ctx)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	snapshot, err := repository.fetchQuery(ctx)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return getEmpty[T](), err
+	}
+	// keep any snapshot taken during the read
+	err = repository.commit(ctx)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	return snapshot.Data.(T), nil
+}
+
+// queries are singletons so the type name is the only identifier
+func (repository QueryRepository[T]) getIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"query": repository.ActorName}
+}
+
+func (repository QueryRepository[T]) fetchQuery(ctx context.Context) (Snapshot, error) {
+	ids := repository.getIdentifiers()
+
+	// get the latest snapshot or initialize and empty
+	snapshot, _, err := repository.getLatestSnapshot(ctx, ids)
+	if err != nil {
+		return snapshot, err
+	}
+
+	// check for all source events since each checkpoint
+	records, err := repository.getSourceEventsSince(ctx, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	// apply events to query instance
+	repository.updateQuery(records, &snapshot)
+
+	// queries are only ever read, so snapshots are taken during
+	// reads once enough events have been applied
+	config := spry.GetActorMeta[T]()
+	if snapshot.EventSinceSnapshot >= config.GetSnapshotFrequency() {
+		err = repository.Storage.AddMap(ctx, repository.ActorName, ids, snapshot.ActorId)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.EventSinceSnapshot = 0
+		err = repository.addSnapshot(ctx, snapshot)
+	}
+	return snapshot, err
+}
+
+func (repository QueryRepository[T]) getSourceEventsSince(ctx context.Context, snapshot *Snapshot) ([]EventRecord, error) {
+	if snapshot.Checkpoints == nil {
+		snapshot.Checkpoints = map[string]uuid.UUID{}
+	}
+
+	records := []EventRecord{}
+	sources := getEmpty[T]().GetSources()
+	for actorName, eventTypes := range sources {
+		list, err := repository.Storage.FetchAllEventsSince(
+			ctx,
+			actorName,
+			snapshot.Checkpoints[actorName],
+			0,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range list {
+			if consumes(eventTypes, record.Type) {
+				records = append(records, record)
+			}
+		}
+		// skipped events still move the checkpoint forward
+		if len(list) > 0 {
+			snapshot.Checkpoints[actorName] = list[len(list)-1].Id
+		}
+	}
+
+	// events are applied in the order they were committed
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Position.Before(records[j].Position)
+	})
+	return records, nil
+}
+
+func (repository QueryRepository[T]) updateQuery(records []EventRecord, snapshot *Snapshot) {
+	if len(records) == 0 {
+		return
+	}
+	events := make([]spry.Event, len(records))
+	for i, record := range records {
+		events[i] = record.Data.(spry.Event)
+	}
+	last := records[len(records)-1]
+	snapshot.Data = repository.Apply(events, snapshot.Data.(T))
+	snapshot.EventsApplied += uint64(len(records))
+	snapshot.EventSinceSnapshot += len(records)
+	snapshot.LastEventId = last.Id
+	snapshot.LastEventOn = last.CreatedOn
+	snapshot.Version++
+}
+
+func consumes(eventTypes []string, eventType string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func GetQueryRepositoryFor[T spry.Query[T]](storage Storage) QueryRepository[T] {
+	actorType := reflect.TypeOf(*new(T))
+	actorName := actorType.Name()
+	return QueryRepository[T]{
+		Repository: Repository[T]{
+			ActorType: actorType,
+			ActorName: actorName,
+			Storage:   storage,
+		},
+	}
+}
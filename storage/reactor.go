@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runs with the events of each command once they are committed, e.g. to
+// update caches or send notifications. The events are shared between
+// reactors and must not be modified.
+type Reactor = func(context.Context, []EventRecord) error
+
+type ReactorConfig struct {
+	// how many goroutines run the reactor; with none it runs
+	// synchronously before Handle returns
+	Workers int
+	// how many commits can wait for a worker before Handle blocks
+	QueueSize int
+	// called with any error the reactor returns or panic it raises;
+	// the command it reacted to is already committed either way
+	OnError func(error)
+}
+
+type reaction struct {
+	ctx    context.Context
+	events []EventRecord
+}
+
+type reactor struct {
+	react   Reactor
+	config  ReactorConfig
+	queue   chan reaction
+	lock    sync.RWMutex
+	stopped bool
+	workers sync.WaitGroup
+}
+
+func (r *reactor) run(ctx context.Context, events []EventRecord) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.report(fmt.Errorf("reactor panicked: %v", p))
+		}
+	}()
+	if err := r.react(ctx, events); err != nil {
+		r.report(err)
+	}
+}
+
+func (r *reactor) report(err error) {
+	if r.config.OnError != nil {
+		r.config.OnError(err)
+	}
+}
+
+func (r *reactor) work() {
+	defer r.workers.Done()
+	for next := range r.queue {
+		r.run(next.ctx, next.events)
+	}
+}
+
+// queues the events unless the reactor has been stopped; holding the
+// read lock keeps stop from closing the queue during the send
+func (r *reactor) enqueue(next reaction) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if !r.stopped {
+		r.queue <- next
+	}
+}
+
+// closes the queue and waits for the workers to finish what was queued
+func (r *reactor) stop() {
+	r.lock.Lock()
+	if r.stopped {
+		r.lock.Unlock()
+		return
+	}
+	r.stopped = true
+	if r.queue != nil {
+		close(r.queue)
+	}
+	r.lock.Unlock()
+	r.workers.Wait()
+}
+
+// the reactors registered on a Storage
+type Reactors struct {
+	lock     sync.RWMutex
+	reactors []*reactor
+}
+
+// registers the reactor and returns a func that unregisters it, which
+// waits for its workers to run whatever was already queued
+func (reactors *Reactors) Add(react Reactor, config ReactorConfig) func() {
+	r := &reactor{react: react, config: config}
+	if config.Workers > 0 {
+		r.queue = make(chan reaction, config.QueueSize)
+		r.workers.Add(config.Workers)
+		for i := 0; i < config.Workers; i++ {
+			go r.work()
+		}
+	}
+	reactors.lock.Lock()
+	defer reactors.lock.Unlock()
+	reactors.reactors = append(reactors.reactors, r)
+	return func() {
+		reactors.remove(r)
+		r.stop()
+	}
+}
+
+func (reactors *Reactors) remove(r *reactor) {
+	reactors.lock.Lock()
+	defer reactors.lock.Unlock()
+	list := []*reactor{}
+	for _, other := range reactors.reactors {
+		if other != r {
+			list = append(list, other)
+		}
+	}
+	reactors.reactors = list
+}
+
+func (reactors *Reactors) Run(ctx context.Context, events []EventRecord) {
+	if len(events) == 0 {
+		return
+	}
+	reactors.lock.RLock()
+	list := reactors.reactors
+	reactors.lock.RUnlock()
+
+	ctx = detached{ctx}
+	for _, r := range list {
+		if r.queue != nil {
+			r.enqueue(reaction{ctx: ctx, events: events})
+		} else {
+			r.run(ctx, events)
+		}
+	}
+}
+
+// keeps ctx's values but not its deadline, cancellation or the committed
+// transaction, since reactors can run after the caller has returned
+type detached struct {
+	parent context.Context
+}
+
+func (ctx detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (ctx detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (ctx detached) Err() error {
+	return nil
+}
+
+func (ctx detached) Value(key any) any {
+	if key == tx_key {
+		return nil
+	}
+	return ctx.parent.Value(key)
+}
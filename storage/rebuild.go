@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+type RebuildOptions struct {
+	// the number of actors rebuilt at once, 1 when not set
+	Workers int
+	// replays every actor and reports which would change without
+	// writing anything
+	DryRun bool
+	// called as each actor is finished with the number done so far
+	// and the number to do. Calls may come from any worker.
+	Progress func(done int, total int)
+}
+
+type RebuildReport struct {
+	// the number of actors that were replayed
+	Rebuilt int
+	// the current identifiers of each actor whose replayed state
+	// differs from the state its snapshots and events give today
+	Changed []spry.Identifiers
+}
+
+// replays an actor from every one of its events and, unless it's a dry
+// run, replaces its snapshots with one of the result
+func (repository Repository[T]) rebuildActor(
+	ctx context.Context,
+	actorId uuid.UUID,
+	fetchEvents func(context.Context, Snapshot) ([]spry.Event, []EventRecord, error),
+	dryRun bool) (bool, error) {
+	ctx, err := repository.Storage.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	// nothing is committed unless a snapshot is written
+	defer func() { _ = repository.Storage.Rollback(ctx) }()
+
+	current, err := repository.replay(ctx, actorId, func(ctx context.Context, uid uuid.UUID) (Snapshot, error) {
+		return repository.Storage.FetchLatestSnapshot(ctx, repository.ActorName, uid)
+	}, fetchEvents)
+	if err != nil {
+		return false, err
+	}
+	rebuilt, err := repository.replay(ctx, actorId, func(context.Context, uuid.UUID) (Snapshot, error) {
+		return Snapshot{}, nil
+	}, fetchEvents)
+	if err != nil {
+		return false, err
+	}
+	changed, err := differs(current.Data, rebuilt.Data)
+	if err != nil {
+		return false, err
+	}
+	if dryRun || rebuilt.EventsApplied == 0 {
+		return changed, nil
+	}
+
+	err = repository.Storage.SupersedeSnapshots(ctx, repository.ActorName, actorId)
+	if err != nil {
+		return changed, err
+	}
+	err = repository.addSnapshot(ctx, rebuilt)
+	if err != nil {
+		return changed, err
+	}
+	return changed, repository.commit(ctx)
+}
+
+// compares the states as JSON, since a snapshot read back from storage
+// can hold an empty slice or map where the replayed state has nil
+func differs(current any, rebuilt any) (bool, error) {
+	a, err := normalizedJson(current)
+	if err != nil {
+		return false, err
+	}
+	b, err := normalizedJson(rebuilt)
+	if err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(a, b), nil
+}
+
+func normalizedJson(state any) (any, error) {
+	bytes, err := spry.ToJson(state)
+	if err != nil {
+		return nil, err
+	}
+	value, err := spry.FromJson[any](bytes)
+	if err != nil {
+		return nil, err
+	}
+	return dropEmpty(value), nil
+}
+
+// nulls, empty lists and empty objects all read as absent
+func dropEmpty(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if field = dropEmpty(field); field == nil {
+				delete(v, key)
+			} else {
+				v[key] = field
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, item := range v {
+			v[i] = dropEmpty(item)
+		}
+	}
+	return value
+}
+
+// the actor built from the snapshot fetchSnapshot finds and the events after it
+func (repository Repository[T]) replay(
+	ctx context.Context,
+	actorId uuid.UUID,
+	fetchSnapshot func(context.Context, uuid.UUID) (Snapshot, error),
+	fetchEvents func(context.Context, Snapshot) ([]spry.Event, []EventRecord, error)) (Snapshot, error) {
+	snapshot, err := repository.getSnapshotByUUID(ctx, actorId, fetchSnapshot)
+	if err != nil {
+		return snapshot, err
+	}
+	events, records, err := fetchEvents(ctx, snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+	repository.updateActor(events, records, &snapshot)
+	return snapshot, nil
+}
+
+// the identifiers the actor is known by now
+func (repository Repository[T]) currentIds(ctx context.Context, actorId uuid.UUID) (spry.Identifiers, error) {
+	ctx, err := repository.Storage.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = repository.Storage.Rollback(ctx) }()
+	history, err := repository.Storage.FetchIdHistory(ctx, repository.ActorName, actorId)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].IsCurrent() {
+			return history[i].Identifiers, nil
+		}
+	}
+	if len(history) > 0 {
+		return history[len(history)-1].Identifiers, nil
+	}
+	return spry.Identifiers{}, nil
+}
+
+// rebuilds every actor of the repository's type from its events, spreading
+// them across the workers options asks for. Each actor is rebuilt in its
+// own transaction so the first error stops the work without undoing what
+// was already rebuilt.
+func (repository Repository[T]) rebuildAll(
+	ctx context.Context,
+	fetchEvents func(context.Context, Snapshot) ([]spry.Event, []EventRecord, error),
+	options RebuildOptions) (RebuildReport, error) {
+	report := RebuildReport{Changed: []spry.Identifiers{}}
+	actorIds, err := func() ([]uuid.UUID, error) {
+		ctx, err := repository.Storage.GetContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = repository.Storage.Rollback(ctx) }()
+		return repository.Storage.FetchActorIds(ctx, repository.ActorName)
+	}()
+	if err != nil {
+		return report, err
+	}
+
+	workers := options.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan uuid.UUID)
+	lock := sync.Mutex{}
+	var failed error
+	wait := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for actorId := range queue {
+				changed, err := repository.rebuildActor(ctx, actorId, fetchEvents, options.DryRun)
+				var ids spry.Identifiers
+				if err == nil && changed {
+					ids, err = repository.currentIds(ctx, actorId)
+				}
+
+				lock.Lock()
+				if err != nil {
+					if failed == nil {
+						failed = err
+						cancel()
+					}
+					lock.Unlock()
+					continue
+				}
+				report.Rebuilt++
+				if changed {
+					report.Changed = append(report.Changed, ids)
+				}
+				done := report.Rebuilt
+				lock.Unlock()
+				if options.Progress != nil {
+					options.Progress(done, len(actorIds))
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, actorId := range actorIds {
+		select {
+		case queue <- actorId:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wait.Wait()
+
+	if failed == nil {
+		failed = ctx.Err()
+	}
+	return report, failed
+}
+
+// replaces the snapshots of every actor of this type with ones replayed
+// from their events, for when an Apply has been fixed
+func (repository ActorRepository[T]) Rebuild(ctx context.Context, options RebuildOptions) (RebuildReport, error) {
+	return repository.rebuildAll(ctx, repository.actorEvents, options)
+}
+
+// replaces the snapshots of every aggregate of this type with ones
+// replayed from their events and their children's
+func (repository AggregateRepository[T]) Rebuild(ctx context.Context, options RebuildOptions) (RebuildReport, error) {
+	return repository.rebuildAll(ctx, repository.aggregateEvents, options)
+}
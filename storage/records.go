@@ -26,6 +26,8 @@ type Snapshot struct {
 	ActorId uuid.UUID `json:"actorId"`
 	// the type name of the actor
 	Type string `json:"type"`
+	// identifies the shape of the actor type the snapshot was taken from
+	Fingerprint string `json:"fingerprint"`
 	// a serialized causal tracker
 	Vector string `json:"vector"`
 	// a numeric version of the model
@@ -38,6 +40,11 @@ type Snapshot struct {
 	EventsApplied uint64 `json:"eventsApplied"`
 	// the number of events since the last snapshot was created
 	EventSinceSnapshot int
+	// when a tombstone event ended the actor's life
+	DeletedOn time.Time `json:"deletedOn"`
+	// the UUID of the last event consumed from each source actor type
+	// (only used by queries)
+	Checkpoints map[string]uuid.UUID `json:"checkpoints"`
 	// the UUID of the last event played against the instance
 	LastEventId uuid.UUID `json:"lastEventId"`
 	// the UUID of the last command handled
@@ -54,6 +61,10 @@ func (snapshot Snapshot) IsValid() bool {
 	return !snapshot.Id.IsNil()
 }
 
+func (snapshot Snapshot) IsDeleted() bool {
+	return !snapshot.DeletedOn.IsZero()
+}
+
 func NewSnapshot(actor any) (Snapshot, error) {
 	actorType := reflect.TypeOf(actor)
 	actorName := actorType.Name()
@@ -63,13 +74,13 @@ func NewSnapshot(actor any) (Snapshot, error) {
 	}
 
 	return Snapshot{
-		Id:        id,
-		Type:      actorName,
-		CreatedOn: time.Now().UTC(),
-		Data:      actor,
-		LastEventMap: LastEventMap{
-			LastEvents: map[string]map[uuid.UUID]uuid.UUID{},
-		},
+		Id:           id,
+		Type:         actorName,
+		Fingerprint:  GetFingerprint(actor),
+		CreatedOn:    time.Now().UTC(),
+		Data:         actor,
+		LastEventMap: CreateLastEvents(),
+		Checkpoints:  map[string]uuid.UUID{},
 	}, nil
 }
 
@@ -94,12 +105,33 @@ type EventRecord struct {
 	CreatedByVector string `json:"createdByVector"`
 	// the version of the snapshot instantiating the event
 	CreatedByVersion uint64 `json:"createdByVersion"`
+	// the position of the event within the owning actor's stream
+	Version uint64 `json:"version"`
+	// the version of the event type's schema the contents were written with
+	SchemaVersion int `json:"schemaVersion"`
 	// the command type/topic that triggered the event
 	InitiatedBy string `json:"initiatedBy"`
 	// the id of the message that triggered the event
 	InitiatedById uuid.UUID `json:"initiatedById"`
 	// the contents of the event
 	Data any `json:"data"`
+	// where the event was committed among the actor type's events, only
+	// set on events read by FetchAllEventsSince
+	Position EventPosition `json:"-"`
+}
+
+// orders events by the transaction that committed them, then by id.
+// Ids are assigned before commit, so they alone can't order a feed.
+type EventPosition struct {
+	Tx uint64
+	Id uuid.UUID
+}
+
+func (position EventPosition) Before(other EventPosition) bool {
+	if position.Tx != other.Tx {
+		return position.Tx < other.Tx
+	}
+	return position.Id.String() < other.Id.String()
 }
 
 func (event EventRecord) IsValid() bool {
@@ -140,10 +172,15 @@ type CommandRecord struct {
 	ReceivedOn time.Time `json:"receivedOn"`
 	// the time the command was handled
 	HandledOn time.Time `json:"handledOn"`
-	// the id of the recipient actor
+	// the id of the recipient actor, nil when the command didn't
+	// create the actor it was sent to
 	HandledBy uuid.UUID `json:"handledBy"`
+	// the identifiers the command was sent to when there's no HandledBy
+	Identifiers spry.Identifiers `json:"identifiers,omitempty"`
 	// the version of the actor that handled the command
 	HandledVersion uint64
+	// the reasons the actor gave for rejecting the command
+	Errors []string `json:"errors"`
 	// the contents of the command
 	Data any `json:"data"`
 }
@@ -152,6 +189,13 @@ func (command CommandRecord) IsValid() bool {
 	return command.Id.IsNil()
 }
 
+func (command *CommandRecord) SetErrors(errs []error) {
+	command.Errors = make([]string, len(errs))
+	for i, err := range errs {
+		command.Errors[i] = err.Error()
+	}
+}
+
 func NewCommandRecord(command spry.Command) (CommandRecord, error) {
 	commandType := reflect.TypeOf(command)
 	commandName := commandType.Name()
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -40,11 +41,71 @@ func (repository Repository[T]) createCommandRecord(command spry.Command, baseli
 	cmdRecord.HandledBy = baseline.ActorId
 	cmdRecord.HandledVersion = baseline.Version
 	cmdRecord.HandledOn = time.Now()
+	cmdRecord.ReceivedOn = cmdRecord.HandledOn
 	return cmdRecord, spry.Results[T]{}, false
 }
 
+// an actor without events is never stored, so commands that didn't
+// create it are logged against the identifiers they were sent to rather
+// than an id nothing holds
+func unhandled(cmdRecord CommandRecord, baseline Snapshot, ids spry.Identifiers) CommandRecord {
+	if baseline.EventsApplied > 0 {
+		return cmdRecord
+	}
+	cmdRecord.HandledBy = uuid.Nil
+	cmdRecord.HandledVersion = 0
+	cmdRecord.Identifiers = ids
+	return cmdRecord
+}
+
+// commands that were rejected or produced no events still belong in the
+// command log even though there is nothing else to write
+func (repository Repository[T]) storeCommand(
+	ctx context.Context,
+	cmdRecord CommandRecord,
+	baseline Snapshot,
+	ids spry.Identifiers,
+	results spry.Results[T]) spry.Results[T] {
+	cmdRecord = unhandled(cmdRecord, baseline, ids)
+	cmdRecord.SetErrors(results.Errors)
+	err := repository.Storage.AddCommand(ctx, repository.ActorName, cmdRecord)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		results.Errors = append(results.Errors, err)
+		return results
+	}
+	err = repository.commit(ctx)
+	if err != nil {
+		results.Errors = append(results.Errors, err)
+	}
+	return results
+}
+
+func (repository Repository[T]) deleted(snapshot Snapshot) error {
+	return ErrActorDeleted{
+		ActorName: repository.ActorName,
+		ActorId:   snapshot.ActorId,
+		DeletedOn: snapshot.DeletedOn,
+	}
+}
+
+// commits the transaction held by ctx unless the caller cancelled ctx
+// first, in which case nothing written under it is kept
+func (repository Repository[T]) commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		_ = repository.Storage.Rollback(ctx)
+		return err
+	}
+	err := repository.Storage.Commit(ctx)
+	if err != nil {
+		_ = repository.Storage.Rollback(ctx)
+	}
+	return err
+}
+
 func (repository Repository[T]) createEventRecords(events []spry.Event, baseline Snapshot, cmdRecord CommandRecord, assignments IdAssignments) ([]EventRecord, spry.Results[T], bool) {
 	eventRecords := make([]EventRecord, len(events))
+	versions := map[uuid.UUID]uint64{}
 	for i, event := range events {
 		record, err := NewEventRecord(event)
 		if err != nil {
@@ -61,7 +122,14 @@ func (repository Repository[T]) createEventRecords(events []spry.Event, baseline
 			record.ActorName = child
 			record.CreatedBy = eventMeta.CreatedBy
 
-			identifiers := event.(spry.Aggregate[T]).GetIdentifierSet()
+			aggregated, ok := event.(spry.Aggregate[T])
+			if !ok {
+				return nil, spry.Results[T]{
+					Original: baseline.Data.(T),
+					Errors:   []error{fmt.Errorf("%s must implement GetIdentifierSet to be created for %s", record.Type, child)},
+				}, true
+			}
+			identifiers := aggregated.GetIdentifierSet()
 			idSet := spry.IdSetFromIdentifierSet(identifiers)
 			childIds := idSet.GetIdsFor(child)[0]
 			record.ActorId = assignments.GetIdFor(child, childIds)
@@ -75,6 +143,7 @@ func (repository Repository[T]) createEventRecords(events []spry.Event, baseline
 			record.ActorId = baseline.ActorId
 		}
 
+		record.Version = nextVersion(baseline, record.ActorName, record.ActorId, versions)
 		record.CreatedById = baseline.ActorId
 		record.CreatedByVersion = baseline.Version
 		record.CreatedOn = time.Now()
@@ -86,6 +155,22 @@ func (repository Repository[T]) createEventRecords(events []spry.Event, baseline
 	return eventRecords, spry.Results[T]{}, false
 }
 
+// each event takes the next position in the stream it is written to,
+// starting from the last version observed when the baseline was loaded
+func nextVersion(baseline Snapshot, actorName string, actorId uuid.UUID, versions map[uuid.UUID]uint64) uint64 {
+	version, ok := versions[actorId]
+	if !ok {
+		if actorId == baseline.ActorId {
+			version = baseline.Version
+		} else {
+			version = baseline.GetLastVersionFor(actorName, actorId)
+		}
+	}
+	version++
+	versions[actorId] = version
+	return version
+}
+
 func (repository Repository[T]) fetchActor(ctx context.Context, ids spry.Identifiers) (Snapshot, error) {
 
 	// get the latest snapshot or initialize and empty
@@ -197,6 +282,17 @@ func (repository Repository[T]) getLatestSnapshot(ctx context.Context, ids spry.
 }
 
 func (repository Repository[T]) getLatestSnapshotByUUID(ctx context.Context, uid uuid.UUID) (Snapshot, error) {
+	return repository.getSnapshotByUUID(ctx, uid, func(ctx context.Context, uid uuid.UUID) (Snapshot, error) {
+		return repository.Storage.FetchLatestSnapshot(ctx, repository.ActorName, uid)
+	})
+}
+
+// uses fetch to find a snapshot to start from, falling back to an
+// empty snapshot when there isn't a usable one
+func (repository Repository[T]) getSnapshotByUUID(
+	ctx context.Context,
+	uid uuid.UUID,
+	fetch func(context.Context, uuid.UUID) (Snapshot, error)) (Snapshot, error) {
 	// create an empty actor instance and empty snapshot
 	empty := getEmpty[T]()
 	snapshot, err := NewSnapshot(empty)
@@ -206,11 +302,16 @@ func (repository Repository[T]) getLatestSnapshotByUUID(ctx context.Context, uid
 
 	// fetch the latest snapshot from storage or return empty
 	if uid != uuid.Nil {
-		latest, err := repository.Storage.FetchLatestSnapshot(ctx, repository.ActorName, uid)
+		latest, err := fetch(ctx, uid)
 		if err != nil {
 			return snapshot, err
 		}
-		if latest.IsValid() {
+		// snapshots of an older actor shape are rebuilt from events
+		if latest.IsValid() && latest.Fingerprint == snapshot.Fingerprint {
+			latest.Data, err = asActor[T](latest.Data)
+			if err != nil {
+				return snapshot, err
+			}
 			snapshot = latest
 		} else {
 			snapshot.ActorId = uid
@@ -224,19 +325,49 @@ func (repository Repository[T]) getLatestSnapshotByUUID(ctx context.Context, uid
 	return snapshot, nil
 }
 
+// disk backed stores can't know the actor type when decoding a snapshot,
+// so its data is decoded again into T when it isn't one already
+func asActor[T any](data any) (T, error) {
+	if actor, ok := data.(T); ok {
+		return actor, nil
+	}
+	buffer, err := spry.ToJson(data)
+	if err != nil {
+		return *new(T), err
+	}
+	return spry.FromJson[T](buffer)
+}
+
 func (repository Repository[T]) updateActor(events []spry.Event, records []EventRecord, snapshot *Snapshot) {
 	actor := snapshot.Data.(T)
 	next := repository.Apply(events, actor)
 	eventCount := len(events)
 	if eventCount > 0 {
 		snapshot.EventsApplied += uint64(eventCount)
+		snapshot.EventSinceSnapshot += eventCount
 		last := records[len(records)-1]
 		snapshot.LastEventOn = last.CreatedOn
 		snapshot.LastEventId = last.Id
-		snapshot.Version++
 		snapshot.Data = next
 	}
 
+	for _, record := range records {
+		if record.ActorId == snapshot.ActorId {
+			if _, ok := record.Data.(spry.Tombstone); ok {
+				snapshot.DeletedOn = record.CreatedOn
+			}
+			// records written before versioning existed carry no position
+			if record.Version > 0 {
+				snapshot.Version = record.Version
+			} else {
+				snapshot.Version++
+			}
+		} else {
+			snapshot.AddLastEventFor(record.ActorName, record.ActorId, record.Id)
+			snapshot.AddLastVersionFor(record.ActorName, record.ActorId, record.Version)
+		}
+	}
+
 	if snapshot.ActorId == uuid.Nil {
 		snapshot.ActorId, _ = GetId()
 	}
@@ -249,15 +380,26 @@ func (repository Repository[T]) writeSnapshot(ctx context.Context, eventCount in
 	var err error = nil
 	if config.SnapshotDuringRead &&
 		eventCount > config.SnapshotFrequency {
-		snapshot.EventSinceSnapshot = 0
 		// ignore any error creating snapshots during read
-		err = repository.Storage.AddSnapshot(
-			ctx,
-			repository.ActorName,
-			snapshot,
-			config.SnapshotDuringPartition,
-		)
+		err = repository.addSnapshot(ctx, snapshot)
 	}
 
 	return err
 }
+
+// a loaded snapshot that has since been updated is stored as a new record
+func (repository Repository[T]) addSnapshot(ctx context.Context, snapshot Snapshot) error {
+	id, err := GetId()
+	if err != nil {
+		return err
+	}
+	snapshot.Id = id
+	snapshot.CreatedOn = time.Now().UTC()
+	snapshot.EventSinceSnapshot = 0
+	return repository.Storage.AddSnapshot(
+		ctx,
+		repository.ActorName,
+		snapshot,
+		spry.GetActorMeta[T]().SnapshotDuringPartition,
+	)
+}
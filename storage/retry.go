@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/legitbiz/spry"
+)
+
+func hasConflict(errs []error) bool {
+	for _, err := range errs {
+		var conflict ErrConcurrencyConflict
+		if errors.As(err, &conflict) {
+			return true
+		}
+	}
+	return false
+}
+
+func retryDelay(config spry.ActorMeta, retry int) time.Duration {
+	// doubling stops at the cap so long retry runs can't overflow
+	maxBackoff := config.GetMaxBackoff()
+	delay := config.RetryBackoff
+	for i := 1; i < retry && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	if config.RetryJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(config.RetryJitter)))
+	}
+	return delay
+}
+
+// commands are pure functions of actor state, so an attempt that lost
+// the race to another writer can be re-run against a freshly loaded actor.
+// Returns ctx's error if it is cancelled while waiting to retry.
+func retryConflicts(ctx context.Context, config spry.ActorMeta, attempt func() []error) error {
+	errs := attempt()
+	for retry := 1; retry < config.RetryAttempts && hasConflict(errs); retry++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay(config, retry)):
+		}
+		errs = attempt()
+	}
+	return nil
+}
+
+func withRetries[T any](ctx context.Context, config spry.ActorMeta, attempt func() spry.Results[T]) spry.Results[T] {
+	var results spry.Results[T]
+	err := retryConflicts(ctx, config, func() []error {
+		results = attempt()
+		return results.Errors
+	})
+	if err != nil {
+		results.Errors = append(results.Errors, err)
+	}
+	return results
+}
+
+// a batch is stored in one transaction, so a conflict in any
+// of its results means the whole batch is attempted again
+func withBatchRetries[T any](ctx context.Context, config spry.ActorMeta, attempt func() []spry.Results[T]) []spry.Results[T] {
+	var batch []spry.Results[T]
+	err := retryConflicts(ctx, config, func() []error {
+		batch = attempt()
+		errs := []error{}
+		for _, results := range batch {
+			errs = append(errs, results.Errors...)
+		}
+		return errs
+	})
+	if err != nil {
+		failBatch(batch, err)
+	}
+	return batch
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
@@ -23,26 +24,55 @@ func GetTx[T any](ctx context.Context) T {
 	return ctx.Value(tx_key).(T)
 }
 
+// like GetTx but reports whether ctx holds a transaction of type T
+func LookupTx[T any](ctx context.Context) (T, bool) {
+	tx, ok := ctx.Value(tx_key).(T)
+	return tx, ok
+}
+
 type CommandStore interface {
 	Add(context.Context, string, CommandRecord) error
+	// the commands with the given ids, in no particular order
+	Fetch(context.Context, string, []uuid.UUID, TypeMap) ([]CommandRecord, error)
 }
 
 type EventStore interface {
 	Add(context.Context, []EventRecord) error
 	FetchAggregatedSince(context.Context, string, uuid.UUID, uuid.UUID, LastEventMap, TypeMap) ([]EventRecord, error)
+	FetchAllSince(context.Context, string, uuid.UUID, int, TypeMap) ([]EventRecord, error)
 	FetchSince(context.Context, string, uuid.UUID, uuid.UUID, TypeMap) ([]EventRecord, error)
 }
 
 type MapStore interface {
 	AddId(context.Context, string, spry.Identifiers, uuid.UUID) error
 	AddLink(context.Context, string, uuid.UUID, string, uuid.UUID) error
+	// every actor id stored for the actor type
+	GetActorIds(context.Context, string) ([]uuid.UUID, error)
 	GetId(context.Context, string, spry.Identifiers) (uuid.UUID, error)
+	GetIdHistory(context.Context, string, uuid.UUID) ([]IdentifierRecord, error)
 	GetIdMap(context.Context, string, uuid.UUID) (AggregateIdMap, error)
+	Rekey(context.Context, string, uuid.UUID, spry.Identifiers, bool, time.Time) error
+	RemoveLink(context.Context, string, uuid.UUID, string, uuid.UUID, time.Time) error
+	RetireIds(context.Context, string, uuid.UUID, time.Time) error
+}
+
+type OutboxStore interface {
+	Add(context.Context, []EventRecord) error
+	FetchPending(context.Context, string, int, TypeMap) ([]OutboxRecord, error)
+	// deletes the record so that the outbox only holds unpublished events
+	MarkPublished(context.Context, string, uuid.UUID) error
+	MarkUnpublished(context.Context, string, uuid.UUID, time.Time) error
 }
 
 type SnapshotStore interface {
 	Add(context.Context, string, Snapshot, bool) error
 	Fetch(context.Context, string, uuid.UUID) (Snapshot, error)
+	// the newest snapshot whose last event was applied at or before the time
+	FetchAsOf(context.Context, string, uuid.UUID, time.Time) (Snapshot, error)
+	// the newest snapshot at or before the version
+	FetchAtVersion(context.Context, string, uuid.UUID, uint64) (Snapshot, error)
+	// keeps the actor's snapshots from being fetched again
+	Supersede(context.Context, string, uuid.UUID, time.Time) error
 }
 
 type TxProvider[T any] interface {
@@ -58,31 +88,85 @@ type Storage interface {
 	AddSnapshot(context.Context, string, Snapshot, bool) error
 	AddLink(context.Context, string, uuid.UUID, string, uuid.UUID) error
 	Commit(context.Context) error
+	Committed(context.Context, []EventRecord)
+	DeleteKey(context.Context, string, uuid.UUID) error
+	ExportRecords(context.Context, string, func(ArchiveRecord) error) error
+	FetchActorIds(context.Context, string) ([]uuid.UUID, error)
 	FetchAggregatedEventsSince(context.Context, string, uuid.UUID, uuid.UUID, LastEventMap) ([]EventRecord, error)
+	FetchAllEventsSince(context.Context, string, uuid.UUID, int) ([]EventRecord, error)
+	FetchCommands(context.Context, string, []uuid.UUID) ([]CommandRecord, error)
 	FetchEventsSince(context.Context, string, uuid.UUID, uuid.UUID) ([]EventRecord, error)
 	FetchId(context.Context, string, spry.Identifiers) (uuid.UUID, error)
+	FetchIdHistory(context.Context, string, uuid.UUID) ([]IdentifierRecord, error)
 	FetchIdMap(context.Context, string, uuid.UUID) (AggregateIdMap, error)
 	FetchLatestSnapshot(context.Context, string, uuid.UUID) (Snapshot, error)
+	FetchOutbox(context.Context, string, int) ([]OutboxRecord, error)
+	FetchSnapshotAsOf(context.Context, string, uuid.UUID, time.Time) (Snapshot, error)
+	FetchSnapshotAtVersion(context.Context, string, uuid.UUID, uint64) (Snapshot, error)
 	GetContext(context.Context) (context.Context, error)
+	ImportRecord(context.Context, ArchiveRecord) error
+	MarkPublished(context.Context, string, uuid.UUID) error
+	MarkUnpublished(context.Context, string, uuid.UUID, time.Time) error
+	OnCommitted(Reactor, ...ReactorConfig) func()
+	RegisterAlias(string, any)
+	RegisterKeyStore(KeyStore)
+	RegisterOutbox(OutboxStore)
 	RegisterPrimitives(...any)
+	RegisterUpcaster(string, int, Upcaster)
+	Rekey(context.Context, string, uuid.UUID, spry.Identifiers, bool) error
+	RemoveLink(context.Context, string, uuid.UUID, string, uuid.UUID) error
+	RetireIds(context.Context, string, uuid.UUID) error
 	Rollback(context.Context) error
+	Subscribe(context.Context, string, uuid.UUID) (<-chan EventRecord, <-chan error)
+	SupersedeSnapshots(context.Context, string, uuid.UUID) error
 }
 
 type Stores[Tx any] struct {
+	Archive      ArchiveStore
 	Commands     CommandStore
 	Events       EventStore
 	Maps         MapStore
+	Outbox       *Outbox
 	Primitives   TypeMap
+	Reactors     *Reactors
+	Shredder     *Shredder
 	Snapshots    SnapshotStore
 	Transactions TxProvider[Tx]
 }
 
 func (storage Stores[Tx]) AddCommand(ctx context.Context, actorName string, command CommandRecord) error {
-	return storage.Commands.Add(ctx, actorName, command)
+	stored, err := storage.Shredder.encryptCommand(ctx, actorName, command)
+	if err != nil {
+		return err
+	}
+	return storage.Commands.Add(ctx, actorName, stored)
+}
+
+func (storage Stores[Tx]) FetchCommands(ctx context.Context, actorName string, commandIds []uuid.UUID) ([]CommandRecord, error) {
+	commands, err := storage.Commands.Fetch(ctx, actorName, commandIds, storage.Primitives)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Shredder.decryptCommands(ctx, actorName, commands)
 }
 
+// events are written to the outbox (when one is registered) in the same
+// transaction so that every stored event is eventually published
 func (storage Stores[Tx]) AddEvents(ctx context.Context, events []EventRecord) error {
-	return storage.Events.Add(ctx, events)
+	for i := range events {
+		events[i].SchemaVersion = storage.Primitives.GetSchemaVersion(events[i].Type)
+	}
+	// the caller keeps the plain events for reactors
+	stored, err := storage.Shredder.encryptRecords(ctx, events)
+	if err != nil {
+		return err
+	}
+	err = storage.Events.Add(ctx, stored)
+	outbox := storage.Outbox.getStore()
+	if err != nil || outbox == nil {
+		return err
+	}
+	return outbox.Add(ctx, stored)
 }
 
 func (storage Stores[Tx]) AddLink(
@@ -99,6 +183,11 @@ func (storage Stores[Tx]) AddMap(ctx context.Context, actorName string, identifi
 }
 
 func (storage Stores[Tx]) AddSnapshot(ctx context.Context, actorName string, snapshot Snapshot, allowPartition bool) error {
+	var err error
+	snapshot.Data, err = storage.Shredder.Encrypt(ctx, actorName, snapshot.ActorId, snapshot.Data)
+	if err != nil {
+		return err
+	}
 	return storage.Snapshots.Add(ctx, actorName, snapshot, allowPartition)
 }
 
@@ -106,24 +195,83 @@ func (storage Stores[Tx]) Commit(ctx context.Context) error {
 	return storage.Transactions.Commit(ctx)
 }
 
+// runs the reactors registered with OnCommitted
+func (storage Stores[Tx]) Committed(ctx context.Context, events []EventRecord) {
+	storage.Reactors.Run(ctx, events)
+}
+
+// deletes the key the actor's personal data was encrypted with so that
+// it reads as Redacted from then on
+func (storage Stores[Tx]) DeleteKey(ctx context.Context, actorName string, actorId uuid.UUID) error {
+	keys := storage.Shredder.getKeys()
+	if keys == nil {
+		return ErrKeyStoreMissing
+	}
+	return keys.Delete(ctx, actorName, actorId)
+}
+
+func (storage Stores[Tx]) FetchActorIds(ctx context.Context, actorName string) ([]uuid.UUID, error) {
+	return storage.Maps.GetActorIds(ctx, actorName)
+}
+
 func (storage Stores[Tx]) FetchAggregatedEventsSince(ctx context.Context, actorName string, actorId uuid.UUID, eventId uuid.UUID, idMap LastEventMap) ([]EventRecord, error) {
-	return storage.Events.FetchAggregatedSince(ctx, actorName, actorId, eventId, idMap, storage.Primitives)
+	records, err := storage.Events.FetchAggregatedSince(ctx, actorName, actorId, eventId, idMap, storage.Primitives)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Shredder.decryptRecords(ctx, records)
+}
+
+func (storage Stores[Tx]) FetchAllEventsSince(ctx context.Context, actorName string, eventId uuid.UUID, limit int) ([]EventRecord, error) {
+	records, err := storage.Events.FetchAllSince(ctx, actorName, eventId, limit, storage.Primitives)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Shredder.decryptRecords(ctx, records)
 }
 
 func (storage Stores[Tx]) FetchEventsSince(ctx context.Context, actorName string, actorId uuid.UUID, eventId uuid.UUID) ([]EventRecord, error) {
-	return storage.Events.FetchSince(ctx, actorName, actorId, eventId, storage.Primitives)
+	records, err := storage.Events.FetchSince(ctx, actorName, actorId, eventId, storage.Primitives)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Shredder.decryptRecords(ctx, records)
 }
 
 func (storage Stores[Tx]) FetchId(ctx context.Context, actorName string, identifiers spry.Identifiers) (uuid.UUID, error) {
 	return storage.Maps.GetId(ctx, actorName, identifiers)
 }
 
+// every set of identifiers the actor has been known by, oldest first
+func (storage Stores[Tx]) FetchIdHistory(ctx context.Context, actorName string, actorId uuid.UUID) ([]IdentifierRecord, error) {
+	return storage.Maps.GetIdHistory(ctx, actorName, actorId)
+}
+
 func (storage Stores[Tx]) FetchIdMap(ctx context.Context, actorName string, actorId uuid.UUID) (AggregateIdMap, error) {
 	return storage.Maps.GetIdMap(ctx, actorName, actorId)
 }
 
 func (storage Stores[Tx]) FetchLatestSnapshot(ctx context.Context, actorName string, actorId uuid.UUID) (Snapshot, error) {
-	return storage.Snapshots.Fetch(ctx, actorName, actorId)
+	snapshot, err := storage.Snapshots.Fetch(ctx, actorName, actorId)
+	return storage.decryptSnapshot(ctx, actorName, actorId, snapshot, err)
+}
+
+func (storage Stores[Tx]) FetchSnapshotAsOf(ctx context.Context, actorName string, actorId uuid.UUID, asOf time.Time) (Snapshot, error) {
+	snapshot, err := storage.Snapshots.FetchAsOf(ctx, actorName, actorId, asOf)
+	return storage.decryptSnapshot(ctx, actorName, actorId, snapshot, err)
+}
+
+func (storage Stores[Tx]) FetchSnapshotAtVersion(ctx context.Context, actorName string, actorId uuid.UUID, version uint64) (Snapshot, error) {
+	snapshot, err := storage.Snapshots.FetchAtVersion(ctx, actorName, actorId, version)
+	return storage.decryptSnapshot(ctx, actorName, actorId, snapshot, err)
+}
+
+func (storage Stores[Tx]) decryptSnapshot(ctx context.Context, actorName string, actorId uuid.UUID, snapshot Snapshot, err error) (Snapshot, error) {
+	if err != nil || !snapshot.IsValid() {
+		return snapshot, err
+	}
+	snapshot.Data, err = storage.Shredder.Decrypt(ctx, actorName, actorId, snapshot.Data)
+	return snapshot, err
 }
 
 func (storage Stores[Tx]) GetContext(ctx context.Context) (context.Context, error) {
@@ -134,26 +282,92 @@ func (storage Stores[Tx]) GetContext(ctx context.Context) (context.Context, erro
 	return context.WithValue(ctx, tx_key, newTx), nil
 }
 
+// registers a reactor to run after every commit that stored events;
+// without a config it runs synchronously and its errors are dropped.
+// The returned func stops the reactor once its queued work has run.
+func (storage Stores[Tx]) OnCommitted(reactor Reactor, config ...ReactorConfig) func() {
+	settings := ReactorConfig{}
+	if len(config) > 0 {
+		settings = config[0]
+	}
+	return storage.Reactors.Add(reactor, settings)
+}
+
+func (storage Stores[Tx]) RegisterAlias(oldName string, event any) {
+	storage.Primitives.AddAlias(oldName, event)
+}
+
+// encrypts the fields tagged `spry:"pii"` with keys from keys
+func (storage Stores[Tx]) RegisterKeyStore(keys KeyStore) {
+	storage.Shredder.SetKeys(keys)
+}
+
+// copies every event stored from now on into outbox for a Relay to publish
+func (storage Stores[Tx]) RegisterOutbox(outbox OutboxStore) {
+	storage.Outbox.SetStore(outbox)
+}
+
 func (storage Stores[Tx]) RegisterPrimitives(types ...any) {
 	storage.Primitives.AddTypes(types...)
 }
 
+func (storage Stores[Tx]) RegisterUpcaster(eventType string, fromVersion int, upcaster Upcaster) {
+	storage.Primitives.AddUpcaster(eventType, fromVersion, upcaster)
+}
+
+// makes ids the actor's current identifiers. The identifiers it had
+// before are kept as aliases unless retire is set.
+func (storage Stores[Tx]) Rekey(
+	ctx context.Context,
+	actorName string,
+	actorId uuid.UUID,
+	ids spry.Identifiers,
+	retire bool) error {
+	return storage.Maps.Rekey(ctx, actorName, actorId, ids, retire, time.Now().UTC())
+}
+
+// ends the link between a child and an aggregate; the link is kept so
+// that the child's events from before it ended still belong to the aggregate
+func (storage Stores[Tx]) RemoveLink(
+	ctx context.Context,
+	parentName string,
+	parentId uuid.UUID,
+	childName string,
+	childId uuid.UUID) error {
+	return storage.Maps.RemoveLink(ctx, parentName, parentId, childName, childId, time.Now().UTC())
+}
+
+// stops every identifier the actor has had from resolving to it
+func (storage Stores[Tx]) RetireIds(ctx context.Context, actorName string, actorId uuid.UUID) error {
+	return storage.Maps.RetireIds(ctx, actorName, actorId, time.Now().UTC())
+}
+
 func (storage Stores[Tx]) Rollback(ctx context.Context) error {
 	return storage.Transactions.Rollback(ctx)
 }
 
+// the actor's snapshots are kept but never fetched again
+func (storage Stores[Tx]) SupersedeSnapshots(ctx context.Context, actorName string, actorId uuid.UUID) error {
+	return storage.Snapshots.Supersede(ctx, actorName, actorId, time.Now().UTC())
+}
+
 func NewStorage[Tx any](
 	commands CommandStore,
 	events EventStore,
 	maps MapStore,
 	snapshots SnapshotStore,
 	txs TxProvider[Tx]) Storage {
+	primitives := CreateTypeMap()
+	primitives.AddTypes(spry.ActorDeleted{})
 	return Stores[Tx]{
 		Events:       events,
 		Commands:     commands,
 		Maps:         maps,
+		Outbox:       &Outbox{},
+		Reactors:     &Reactors{},
+		Shredder:     &Shredder{},
 		Snapshots:    snapshots,
 		Transactions: txs,
-		Primitives:   CreateTypeMap(),
+		Primitives:   primitives,
 	}
 }
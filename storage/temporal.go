@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+)
+
+// keeps the events up to a point in an actor's past
+type pastFilter = func([]EventRecord) []EventRecord
+
+// keeps the events created at or before asOf
+func eventsAsOf(asOf time.Time) pastFilter {
+	return func(records []EventRecord) []EventRecord {
+		for i, record := range records {
+			if record.CreatedOn.After(asOf) {
+				return records[:i]
+			}
+		}
+		return records
+	}
+}
+
+// keeps the events that came before the actor's own stream moved past
+// version, so aggregates include what their children did in the meantime
+func eventsAtVersion(actorId uuid.UUID, version uint64) pastFilter {
+	return func(records []EventRecord) []EventRecord {
+		for i, record := range records {
+			if record.ActorId == actorId && record.Version > version {
+				return records[:i]
+			}
+		}
+		return records
+	}
+}
+
+// rebuilds an actor from the snapshot fetchSnapshot finds and the events
+// after it that keep accepts. Reading the past never writes snapshots.
+func (repository Repository[T]) fetchPast(
+	ctx context.Context,
+	actorId uuid.UUID,
+	fetchSnapshot func(context.Context, uuid.UUID) (Snapshot, error),
+	fetchEvents func(context.Context, Snapshot) ([]spry.Event, []EventRecord, error),
+	keep pastFilter) (T, error) {
+	if actorId == uuid.Nil {
+		return getEmpty[T](), nil
+	}
+	snapshot, err := repository.getSnapshotByUUID(ctx, actorId, fetchSnapshot)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	_, records, err := fetchEvents(ctx, snapshot)
+	if err != nil {
+		return getEmpty[T](), err
+	}
+	records = keep(records)
+	events := make([]spry.Event, len(records))
+	for i, record := range records {
+		events[i] = record.Data.(spry.Event)
+	}
+	repository.updateActor(events, records, &snapshot)
+	if snapshot.IsDeleted() {
+		return getEmpty[T](), repository.deleted(snapshot)
+	}
+	return snapshot.Data.(T), nil
+}
+
+func (repository Repository[T]) snapshotAsOf(asOf time.Time) func(context.Context, uuid.UUID) (Snapshot, error) {
+	return func(ctx context.Context, uid uuid.UUID) (Snapshot, error) {
+		return repository.Storage.FetchSnapshotAsOf(ctx, repository.ActorName, uid, asOf)
+	}
+}
+
+func (repository Repository[T]) snapshotAtVersion(version uint64) func(context.Context, uuid.UUID) (Snapshot, error) {
+	return func(ctx context.Context, uid uuid.UUID) (Snapshot, error) {
+		return repository.Storage.FetchSnapshotAtVersion(ctx, repository.ActorName, uid, version)
+	}
+}
+
+// reads what ids identifies in a transaction that is always rolled back
+func readOnly[T any, R any](
+	ctx context.Context,
+	repository Repository[T],
+	ids spry.Identifiers,
+	read func(context.Context, uuid.UUID) (R, error)) (R, error) {
+	ctx, err := repository.Storage.GetContext(ctx)
+	if err != nil {
+		return *new(R), err
+	}
+	defer func() { _ = repository.Storage.Rollback(ctx) }()
+	actorId, err := repository.Storage.FetchId(ctx, repository.ActorName, ids)
+	if err != nil {
+		return *new(R), err
+	}
+	return read(ctx, actorId)
+}
+
+func (repository Repository[T]) actorEvents(ctx context.Context, snapshot Snapshot) ([]spry.Event, []EventRecord, error) {
+	return repository.getEventsSince(ctx, snapshot.ActorId, snapshot)
+}
+
+func (repository Repository[T]) aggregateEvents(ctx context.Context, snapshot Snapshot) ([]spry.Event, []EventRecord, error) {
+	return repository.getAggregatedEventsSince(ctx, snapshot.ActorId, snapshot)
+}
+
+// the actor identified by ids as it was at asOf
+func (repository ActorRepository[T]) FetchAsOf(ids spry.Identifiers, asOf time.Time) (T, error) {
+	return repository.FetchAsOfContext(context.Background(), ids, asOf)
+}
+
+func (repository ActorRepository[T]) FetchAsOfContext(ctx context.Context, ids spry.Identifiers, asOf time.Time) (T, error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) (T, error) {
+		return repository.fetchPast(ctx, actorId, repository.snapshotAsOf(asOf), repository.actorEvents, eventsAsOf(asOf))
+	})
+}
+
+// the actor identified by ids as it was once its stream reached version
+func (repository ActorRepository[T]) FetchAtVersion(ids spry.Identifiers, version uint64) (T, error) {
+	return repository.FetchAtVersionContext(context.Background(), ids, version)
+}
+
+func (repository ActorRepository[T]) FetchAtVersionContext(ctx context.Context, ids spry.Identifiers, version uint64) (T, error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) (T, error) {
+		return repository.fetchPast(ctx, actorId, repository.snapshotAtVersion(version), repository.actorEvents, eventsAtVersion(actorId, version))
+	})
+}
+
+// the aggregate identified by ids as it was at asOf
+func (repository AggregateRepository[T]) FetchAsOf(ids spry.Identifiers, asOf time.Time) (T, error) {
+	return repository.FetchAsOfContext(context.Background(), ids, asOf)
+}
+
+func (repository AggregateRepository[T]) FetchAsOfContext(ctx context.Context, ids spry.Identifiers, asOf time.Time) (T, error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) (T, error) {
+		return repository.fetchPast(ctx, actorId, repository.snapshotAsOf(asOf), repository.aggregateEvents, eventsAsOf(asOf))
+	})
+}
+
+// the aggregate identified by ids as it was while its own stream was at
+// version, including the events its children recorded in that time
+func (repository AggregateRepository[T]) FetchAtVersion(ids spry.Identifiers, version uint64) (T, error) {
+	return repository.FetchAtVersionContext(context.Background(), ids, version)
+}
+
+func (repository AggregateRepository[T]) FetchAtVersionContext(ctx context.Context, ids spry.Identifiers, version uint64) (T, error) {
+	return readOnly(ctx, repository.Repository, ids, func(ctx context.Context, actorId uuid.UUID) (T, error) {
+		return repository.fetchPast(ctx, actorId, repository.snapshotAtVersion(version), repository.aggregateEvents, eventsAtVersion(actorId, version))
+	})
+}
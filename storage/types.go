@@ -10,19 +10,27 @@ import (
 
 type Caster = func(any) (any, error)
 
+// upgrades a stored event payload by one schema version
+type Upcaster = func(map[string]any) (map[string]any, error)
+
 type TypeMap struct {
 	Events   map[string]Caster
 	Commands map[string]Caster
+	// old event type names mapped to the name that replaced them
+	Aliases map[string]string
+	// upcasters for each event type keyed by the version they upgrade from
+	Upcasters map[string]map[int]Upcaster
 }
 
 func (m TypeMap) getCaster(t any) Caster {
+	it := reflect.TypeOf(t)
 	return func(v any) (any, error) {
-		// target := reflect.New(t)
-		err := mapstructure.Decode(v, &t)
+		target := reflect.New(it)
+		err := mapstructure.Decode(v, target.Interface())
 		if err != nil {
-			return t, err
+			return target.Elem().Interface(), err
 		}
-		return t, nil
+		return target.Elem().Interface(), nil
 	}
 }
 
@@ -39,6 +47,76 @@ func (m TypeMap) AddTypes(types ...any) {
 	}
 }
 
+// events stored under oldName will be decoded as the type of event
+func (m TypeMap) AddAlias(oldName string, event any) {
+	m.Aliases[oldName] = reflect.TypeOf(event).Name()
+}
+
+// registers the step that upgrades eventType payloads stored at
+// fromVersion to fromVersion+1
+func (m TypeMap) AddUpcaster(eventType string, fromVersion int, upcaster Upcaster) {
+	if m.Upcasters[eventType] == nil {
+		m.Upcasters[eventType] = map[int]Upcaster{}
+	}
+	m.Upcasters[eventType][fromVersion] = upcaster
+}
+
+// event types start at version 1 and gain a version for each upcaster
+func (m TypeMap) GetSchemaVersion(eventType string) int {
+	version := 1
+	for m.Upcasters[eventType][version] != nil {
+		version++
+	}
+	return version
+}
+
+func (m TypeMap) resolveAlias(eventType string) string {
+	seen := map[string]bool{}
+	for !seen[eventType] {
+		seen[eventType] = true
+		if name, ok := m.Aliases[eventType]; ok {
+			eventType = name
+		}
+	}
+	return eventType
+}
+
+// decodes a stored record's payload into its current event type,
+// following renames and upgrading older payloads one version at a time
+func (m TypeMap) DecodeEvent(record EventRecord) (EventRecord, error) {
+	record.Type = m.resolveAlias(record.Type)
+	current := m.GetSchemaVersion(record.Type)
+	version := record.SchemaVersion
+	// records written before schema versions were tracked
+	if version == 0 {
+		version = 1
+	}
+
+	if data, ok := record.Data.(map[string]any); ok {
+		for ; version < current; version++ {
+			upcast, err := m.Upcasters[record.Type][version](data)
+			if err != nil {
+				return record, fmt.Errorf(
+					"failed to upcast %s from version %d: %w",
+					record.Type,
+					version,
+					err,
+				)
+			}
+			data = upcast
+		}
+		record.Data = data
+	}
+
+	event, err := m.AsEvent(record.Type, record.Data)
+	if err != nil {
+		return record, err
+	}
+	record.Data = event
+	record.SchemaVersion = current
+	return record, nil
+}
+
 func (m TypeMap) AsEvent(eventType string, v any) (spry.Event, error) {
 	if converter, ok := m.Events[eventType]; ok {
 		e, err := converter(v)
@@ -50,6 +128,20 @@ func (m TypeMap) AsEvent(eventType string, v any) (spry.Event, error) {
 	return nil, fmt.Errorf("%s is an unregistered event", eventType)
 }
 
+// decodes a stored command's payload into its registered type. Payloads
+// of unregistered commands are left as they were read.
+func (m TypeMap) DecodeCommand(record CommandRecord) (CommandRecord, error) {
+	if _, ok := m.Commands[record.Type]; !ok {
+		return record, nil
+	}
+	command, err := m.AsCommand(record.Type, record.Data)
+	if err != nil {
+		return record, err
+	}
+	record.Data = command
+	return record, nil
+}
+
 func (m TypeMap) AsCommand(commandType string, v any) (spry.Command, error) {
 	converter := m.Commands[commandType]
 	c, err := converter(v)
@@ -61,7 +153,9 @@ func (m TypeMap) AsCommand(commandType string, v any) (spry.Command, error) {
 
 func CreateTypeMap() TypeMap {
 	return TypeMap{
-		Events:   map[string]Caster{},
-		Commands: map[string]Caster{},
+		Events:    map[string]Caster{},
+		Commands:  map[string]Caster{},
+		Aliases:   map[string]string{},
+		Upcasters: map[string]map[int]Upcaster{},
 	}
 }
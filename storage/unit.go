@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// groups the work of several repositories, of any actor types, into one
+// transaction. Repositories enlist by being created from the unit's
+// Storage; their commits wait for the unit to be committed and any
+// rollback they make fails the whole unit. Like the transaction it holds,
+// a unit should only be used from one goroutine at a time.
+type UnitOfWork struct {
+	lock    sync.Mutex
+	storage Storage
+	ctx     context.Context
+	failed  bool
+	closed  bool
+	// events committed by the unit's repositories, which are only
+	// passed to reactors once the unit itself is committed
+	committed []EventRecord
+}
+
+// begins a unit of work that holds a transaction from storage
+func NewUnitOfWork(ctx context.Context, storage Storage) (*UnitOfWork, error) {
+	txCtx, err := storage.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &UnitOfWork{storage: storage, ctx: txCtx}, nil
+}
+
+// the storage repositories should be created from to enlist in the unit
+func (unit *UnitOfWork) Storage() Storage {
+	return unitStorage{Storage: unit.storage, unit: unit}
+}
+
+// commits everything the unit's repositories wrote. Fails with
+// ErrUnitOfWorkFailed when one of them rolled back, in which case
+// nothing they wrote is kept.
+func (unit *UnitOfWork) Commit() error {
+	err := unit.commit()
+	if err == nil {
+		unit.storage.Committed(unit.ctx, unit.committed)
+	}
+	return err
+}
+
+func (unit *UnitOfWork) commit() error {
+	unit.lock.Lock()
+	defer unit.lock.Unlock()
+	if unit.closed {
+		return ErrUnitOfWorkClosed
+	}
+	unit.closed = true
+	if unit.failed {
+		_ = unit.storage.Rollback(unit.ctx)
+		return ErrUnitOfWorkFailed
+	}
+	if err := unit.ctx.Err(); err != nil {
+		_ = unit.storage.Rollback(unit.ctx)
+		return err
+	}
+	err := unit.storage.Commit(unit.ctx)
+	if err != nil {
+		_ = unit.storage.Rollback(unit.ctx)
+	}
+	return err
+}
+
+// discards everything the unit's repositories wrote
+func (unit *UnitOfWork) Rollback() error {
+	unit.lock.Lock()
+	defer unit.lock.Unlock()
+	if unit.closed {
+		return nil
+	}
+	unit.closed = true
+	return unit.storage.Rollback(unit.ctx)
+}
+
+// reports why the unit can no longer be written to
+func (unit *UnitOfWork) err() error {
+	if unit.failed {
+		return ErrUnitOfWorkFailed
+	}
+	if unit.closed {
+		return ErrUnitOfWorkClosed
+	}
+	return nil
+}
+
+// the unit's transaction carried by ctx, so callers keep
+// their own deadlines and cancellation
+func (unit *UnitOfWork) attach(ctx context.Context) (context.Context, error) {
+	unit.lock.Lock()
+	defer unit.lock.Unlock()
+	if err := unit.err(); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, tx_key, unit.ctx.Value(tx_key)), nil
+}
+
+// the transaction is rolled back when the unit is committed or rolled
+// back so that anything written after the failure is discarded with it
+func (unit *UnitOfWork) fail() {
+	unit.lock.Lock()
+	defer unit.lock.Unlock()
+	unit.failed = true
+}
+
+type unitStorage struct {
+	Storage
+	unit *UnitOfWork
+}
+
+func (storage unitStorage) Commit(ctx context.Context) error {
+	storage.unit.lock.Lock()
+	defer storage.unit.lock.Unlock()
+	return storage.unit.err()
+}
+
+func (storage unitStorage) Committed(ctx context.Context, events []EventRecord) {
+	storage.unit.lock.Lock()
+	defer storage.unit.lock.Unlock()
+	storage.unit.committed = append(storage.unit.committed, events...)
+}
+
+func (storage unitStorage) GetContext(ctx context.Context) (context.Context, error) {
+	return storage.unit.attach(ctx)
+}
+
+func (storage unitStorage) Rollback(ctx context.Context) error {
+	storage.unit.fail()
+	return nil
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"testing"
+	"time"
 
 	"github.com/legitbiz/spry"
 )
@@ -50,3 +51,14 @@ func TestActorWithoutConfig(t *testing.T) {
 		t.Error("actor meta for withit did not match expected settings")
 	}
 }
+
+func TestMaxBackoffDefaultsWhenUnset(t *testing.T) {
+	meta := spry.GetActorMeta[Without]()
+	if meta.GetMaxBackoff() != time.Second {
+		t.Error("expected the default cap on retry backoff", meta.GetMaxBackoff())
+	}
+	meta.MaxBackoff = time.Minute
+	if meta.GetMaxBackoff() != time.Minute {
+		t.Error("expected the configured cap on retry backoff", meta.GetMaxBackoff())
+	}
+}
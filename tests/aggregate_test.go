@@ -2,6 +2,7 @@ package tests
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/legitbiz/spry"
@@ -113,3 +114,38 @@ func TestSnapshotMapUpdate(t *testing.T) {
 		t.Error("last event map did not contain the expected mappings")
 	}
 }
+
+func TestChildEventsAreBoundedByLinkPeriods(t *testing.T) {
+	p1, _ := storage.GetId()
+	c1, _ := storage.GetId()
+	c2, _ := storage.GetId()
+	linked := time.Now()
+	ended := linked.Add(time.Minute)
+
+	aggMap := storage.CreateAggregateIdMap("P", p1)
+	aggMap.AddIdsFor("C", c2)
+	aggMap.AddLinkPeriod("C", c2, linked, time.Time{})
+	aggMap.AddLinkPeriod("C", c1, linked, ended)
+
+	last := storage.CreateLastEvents()
+	last.UpdateFromMap(aggMap)
+	if _, ok := last.LastEvents["C"][c1]; !ok {
+		t.Error("events from before the link ended should still be read")
+	}
+	if !last.IsLinkedOn("C", c1, ended) || last.IsLinkedOn("C", c1, ended.Add(time.Second)) {
+		t.Error("events from the unlinked child should stop where its link ended")
+	}
+	if last.IsLinkedOn("C", c2, linked.Add(-time.Second)) || !last.IsLinkedOn("C", c2, ended.Add(time.Hour)) {
+		t.Error("events from linked children should start where their link started")
+	}
+
+	// linking the child again only brings back the events after it
+	relinked := ended.Add(time.Hour)
+	aggMap.AddIdsFor("C", c1)
+	aggMap.AddLinkPeriod("C", c1, relinked, time.Time{})
+	last.UpdateFromMap(aggMap)
+	if last.IsLinkedOn("C", c1, ended.Add(time.Minute)) ||
+		!last.IsLinkedOn("C", c1, relinked.Add(time.Minute)) {
+		t.Error("events from between a child's links should not belong to the aggregate")
+	}
+}
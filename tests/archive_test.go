@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](source)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+
+	archive := bytes.Buffer{}
+	exported, err := storage.Export(ctx, source, &archive, "Player")
+	// two ids, three commands and three events
+	if err != nil || exported != 8 {
+		t.Fatal("expected every player record to be exported", exported, err)
+	}
+
+	target := memory.InMemoryStorage()
+	target.RegisterPrimitives(PlayerCreated{}, PlayerDamaged{})
+	imported, err := storage.Import(ctx, target, bytes.NewReader(archive.Bytes()))
+	if err != nil || imported != exported {
+		t.Fatal("expected every record to be imported", imported, err)
+	}
+	copies := storage.GetActorRepositoryFor[Player](target)
+	player, err := copies.Fetch(bob)
+	if err != nil || player.HitPoints != 90 {
+		t.Fatal("expected the imported player", player, err)
+	}
+
+	// importing the same archive again stores nothing new
+	again, err := storage.Import(ctx, target, bytes.NewReader(archive.Bytes()))
+	if err != nil || again != exported {
+		t.Fatal("expected the archive to be read again", again, err)
+	}
+	history, _ := copies.History(bob, storage.HistoryOptions{})
+	if len(history) != 2 || history[1].State.HitPoints != 90 {
+		t.Error("expected the imported events once each", history)
+	}
+	if history[0].Command == nil {
+		t.Error("expected the imported commands in the history", history[0])
+	}
+
+	copies.Handle(HealPlayer{Name: "Bob", Health: 5})
+	healed, _ := copies.Fetch(bob)
+	if healed.HitPoints != 95 {
+		t.Error("expected the imported player to keep handling commands", healed)
+	}
+}
+
+func TestArchiveMotoristLinks(t *testing.T) {
+	ctx := context.Background()
+	source := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](source)
+
+	m1id := MotoristId{License: "556677889", State: "OR"}
+	ids := spry.Identifiers{"License": m1id.License, "State": m1id.State}
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "100200300"}, Color: "Red"})
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "700800900"}, Color: "Green"})
+
+	archive := bytes.Buffer{}
+	_, err := storage.Export(ctx, source, &archive, "Motorist", "Vehicle")
+	if err != nil {
+		t.Fatal("failed to export motorists", err)
+	}
+
+	target := memory.InMemoryStorage()
+	target.RegisterPrimitives(VehicleRegistered{}, VehicleSold{})
+	_, err = storage.Import(ctx, target, &archive)
+	if err != nil {
+		t.Fatal("failed to import motorists", err)
+	}
+	motorist, err := storage.GetAggregateRepositoryFor[Motorist](target).Fetch(ids)
+	if err != nil || len(motorist.Vehicles) != 2 {
+		t.Error("expected the imported motorist to keep its vehicles", motorist, err)
+	}
+}
+
+func TestArchiveIdsAreStable(t *testing.T) {
+	ctx := context.Background()
+	source := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](source)
+	m1id := MotoristId{License: "556677889", State: "OR"}
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "100200300"}, Color: "Red"})
+
+	first := bytes.Buffer{}
+	second := bytes.Buffer{}
+	_, _ = storage.Export(ctx, source, &first, "Motorist", "Vehicle")
+	_, _ = storage.Export(ctx, source, &second, "Motorist", "Vehicle")
+	if first.String() != second.String() {
+		t.Error("expected exporting twice to give the same records")
+	}
+
+	// an imported store exports the ids it was given
+	target := memory.InMemoryStorage()
+	target.RegisterPrimitives(VehicleRegistered{}, VehicleSold{})
+	_, err := storage.Import(ctx, target, bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal("failed to import motorists", err)
+	}
+	copied := bytes.Buffer{}
+	_, _ = storage.Export(ctx, target, &copied, "Motorist", "Vehicle")
+	if archivedIds(copied) != archivedIds(first) {
+		t.Error("expected the imported records to keep their ids")
+	}
+}
+
+// the ids of the id and link records in an archive
+func archivedIds(archive bytes.Buffer) string {
+	ids := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(archive.String()), "\n") {
+		record, _ := spry.FromJson[storage.ArchiveRecord]([]byte(line))
+		if record.Kind == storage.ArchivedId || record.Kind == storage.ArchivedLink {
+			archived, _ := spry.FromJson[struct{ Id string }](record.Record)
+			ids = append(ids, archived.Id)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestArchiveRejectsUnknownKinds(t *testing.T) {
+	store := memory.InMemoryStorage()
+	archive := bytes.NewBufferString(`{"kind":"widget","actor":"Player","record":{}}`)
+	_, err := storage.Import(context.Background(), store, archive)
+	if err == nil {
+		t.Error("expected an unknown kind of record to fail the import")
+	}
+}
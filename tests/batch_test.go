@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+type Tally struct {
+	Name  string
+	Total int
+}
+
+func (t Tally) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": t.Name}
+}
+
+func (t Tally) GetActorMeta() spry.ActorMeta {
+	return spry.ActorMeta{
+		SnapshotFrequency:   3,
+		SnapshotDuringWrite: true,
+		BatchPolicy:         spry.SkipRejected,
+	}
+}
+
+type AddToTally struct {
+	Name   string
+	Amount int
+}
+
+func (command AddToTally) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command AddToTally) Handle(actor any) ([]spry.Event, []error) {
+	if command.Amount <= 0 {
+		return nil, []error{errors.New("amount must be positive")}
+	}
+	return []spry.Event{AddedToTally(command)}, nil
+}
+
+type AddedToTally struct {
+	Name   string
+	Amount int
+}
+
+func (event AddedToTally) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Tally:
+		a.Name = event.Name
+		a.Total += event.Amount
+	}
+	return actor
+}
+
+// a command players always reject
+type KickPlayer struct {
+	Name string
+}
+
+func (command KickPlayer) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command KickPlayer) Handle(actor any) ([]spry.Event, []error) {
+	return nil, []error{errors.New("players cannot be kicked")}
+}
+
+func TestHandleAllFoldsCommandsIntoOneActor(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	ids := spry.Identifiers{"name": "Bob"}
+
+	batch := players.HandleAll(
+		ids,
+		CreatePlayer{Name: "Bob"},
+		DamagePlayer{Name: "Bob", Damage: 40},
+		HealPlayer{Name: "Bob", Health: 10},
+	)
+	if len(batch) != 3 {
+		t.Fatalf("expected %d results but got %d", 3, len(batch))
+	}
+	for _, results := range batch {
+		if len(results.Errors) > 0 {
+			t.Fatal(results.Errors)
+		}
+	}
+	if batch[2].Original.HitPoints != 60 || batch[2].Modified.HitPoints != 70 {
+		t.Error("each command should be handled against the state left by the one before it")
+	}
+
+	player, _ := players.Fetch(ids)
+	if player.HitPoints != 70 {
+		t.Errorf("expected stored player to have %d hit points but had %d", 70, player.HitPoints)
+	}
+	commands := getCommands(store)
+	if len(commands["CreatePlayer"]) != 1 ||
+		len(commands["DamagePlayer"]) != 1 ||
+		len(commands["HealPlayer"]) != 1 {
+		t.Error("expected a command record for each command in the batch")
+	}
+
+	// the batch's events should continue the stream without gaps
+	results := players.Handle(DamagePlayer{Name: "Bob", Damage: 5})
+	if len(results.Errors) > 0 || results.Modified.HitPoints != 65 {
+		t.Error("expected commands after the batch to build on it")
+	}
+}
+
+func TestHandleAllAbortsOnRejection(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	ids := spry.Identifiers{"name": "Bob"}
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	batch := players.HandleAll(
+		ids,
+		DamagePlayer{Name: "Bob", Damage: 40},
+		KickPlayer{Name: "Bob"},
+		HealPlayer{Name: "Bob", Health: 10},
+	)
+	for _, results := range batch {
+		if !errors.Is(results.Errors[len(results.Errors)-1], storage.ErrBatchAborted) {
+			t.Error("expected every result to report that the batch was aborted")
+		}
+	}
+
+	player, _ := players.Fetch(ids)
+	commands := getCommands(store)
+	if player.HitPoints != 100 || len(commands["DamagePlayer"]) != 0 || len(commands["HealPlayer"]) != 0 {
+		t.Error("nothing from an aborted batch should be stored")
+	}
+	kicked := commands["KickPlayer"]
+	if len(kicked) != 1 || len(kicked[0].Errors) != 1 || kicked[0].HandledVersion != 1 {
+		t.Error("expected the rejected command to be logged against the actor as it was before the batch", kicked)
+	}
+}
+
+func TestHandleAllRejectsCommandsForOtherActors(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	ids := spry.Identifiers{"name": "Bob"}
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+
+	batch := players.HandleAll(
+		ids,
+		DamagePlayer{Name: "Bob", Damage: 40},
+		DamagePlayer{Name: "Alice", Damage: 40},
+	)
+	var mismatch storage.ErrCommandTargetsOtherActor
+	if len(batch) != 2 ||
+		!errors.As(batch[1].Errors[0], &mismatch) ||
+		!errors.Is(batch[0].Errors[0], storage.ErrBatchAborted) {
+		t.Fatal("expected the command for another actor to abort the batch", batch)
+	}
+
+	bob, _ := players.Fetch(ids)
+	alice, _ := players.Fetch(spry.Identifiers{"name": "Alice"})
+	if bob.HitPoints != 100 || alice.HitPoints != 100 || len(getCommands(store)["DamagePlayer"]) != 0 {
+		t.Error("expected nothing in the batch to be handled", bob, alice)
+	}
+}
+
+func TestHandleAllCanSkipRejectedCommands(t *testing.T) {
+	store := memory.InMemoryStorage()
+	tallies := storage.GetActorRepositoryFor[Tally](store)
+	ids := spry.Identifiers{"name": "votes"}
+
+	batch := tallies.HandleAll(
+		ids,
+		AddToTally{Name: "votes", Amount: 1},
+		AddToTally{Name: "votes", Amount: -1},
+		AddToTally{Name: "votes", Amount: 2},
+		AddToTally{Name: "votes", Amount: 3},
+	)
+	if len(batch[1].Errors) != 1 || len(batch[3].Errors) != 0 {
+		t.Fatal("expected only the rejected command to report an error")
+	}
+
+	tally, _ := tallies.Fetch(ids)
+	if tally.Total != 6 {
+		t.Errorf("expected total of %d but got %d", 6, tally.Total)
+	}
+	commands := getCommands(store)["AddToTally"]
+	rejected := 0
+	for _, command := range commands {
+		if len(command.Errors) > 0 {
+			rejected++
+		}
+	}
+	if len(commands) != 4 || rejected != 1 {
+		t.Error("expected the rejected command to be logged with the rest")
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func getCommands(store storage.Storage) map[string][]storage.CommandRecord {
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	commands := stores.Commands.(*memory.InMemoryCommandStore)
+	byType := map[string][]storage.CommandRecord{}
+	for _, list := range commands.Commands {
+		for _, command := range list {
+			byType[command.Type] = append(byType[command.Type], command)
+		}
+	}
+	return byType
+}
+
+func TestHandledCommandsAreStored(t *testing.T) {
+	store := memory.InMemoryStorage()
+	repo := storage.GetActorRepositoryFor[Player](store)
+	created := repo.Handle(CreatePlayer{Name: "Bob"})
+	damaged := repo.Handle(DamagePlayer{Name: "Bob", Damage: 40})
+	if len(created.Errors) > 0 || len(damaged.Errors) > 0 {
+		t.Fatal("failed to handle commands")
+	}
+
+	commands := getCommands(store)
+	if len(commands["CreatePlayer"]) != 1 || len(commands["DamagePlayer"]) != 1 {
+		t.Fatal("expected one record for each handled command")
+	}
+	create := commands["CreatePlayer"][0]
+	damage := commands["DamagePlayer"][0]
+	if create.HandledBy != damage.HandledBy ||
+		create.Data.(CreatePlayer).Name != "Bob" ||
+		damage.Data.(DamagePlayer).Damage != 40 {
+		t.Error("command records did not capture the handling actor or payload")
+	}
+}
+
+func TestRejectedCommandsAreStored(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+
+	rv := RegisterVehicle{
+		MotoristId: MotoristId{License: "001", State: "TN"},
+		VehicleId:  VehicleId{VIN: "002"},
+		Type:       "Moped",
+	}
+	motorists.Handle(rv)
+	rejected := motorists.Handle(rv)
+	if len(rejected.Errors) == 0 {
+		t.Fatal("expected duplicate registration to be rejected")
+	}
+
+	commands := getCommands(store)["RegisterVehicle"]
+	if len(commands) != 2 {
+		t.Fatalf("expected %d command records but found %d", 2, len(commands))
+	}
+	if len(commands[0].Errors) != 0 ||
+		len(commands[1].Errors) != 1 ||
+		commands[1].Errors[0] != rejected.Errors[0].Error() {
+		t.Error("rejected command did not record the actor's errors")
+	}
+}
+
+func TestCommandsRejectedByNewActorsHaveNoActor(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	rejected := players.Handle(KickPlayer{Name: "Nobody"})
+	if len(rejected.Errors) == 0 {
+		t.Fatal("expected the command to be rejected")
+	}
+	// a batch that stores nothing but its rejections
+	tallies := storage.GetActorRepositoryFor[Tally](store)
+	tallies.HandleAll(spry.Identifiers{"name": "Nobody"}, AddToTally{Name: "Nobody", Amount: -1})
+
+	commands := getCommands(store)
+	logged := append(commands["KickPlayer"], commands["AddToTally"]...)
+	if len(logged) != 2 {
+		t.Fatalf("expected %d command records but found %d", 2, len(logged))
+	}
+	for _, command := range logged {
+		if command.HandledBy != uuid.Nil || command.Identifiers["name"] != "Nobody" {
+			t.Error("expected the command to be logged against its identifiers", command)
+		}
+	}
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	uid, _ := store.FetchId(ctx, "Player", spry.Identifiers{"name": "Nobody"})
+	if uid != uuid.Nil {
+		t.Error("expected no actor to be stored for a rejected command", uid)
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestStaleAppendIsRejected(t *testing.T) {
+	store := memory.InMemoryStorage()
+	ctx, _ := store.GetContext(context.Background())
+	aid, _ := storage.GetId()
+
+	er1, _ := storage.NewEventRecord(PlayerCreated{Name: "Bob"})
+	er1.ActorId = aid
+	er1.ActorName = "Player"
+	er1.Version = 1
+	err := store.AddEvents(ctx, []storage.EventRecord{er1})
+	if err != nil {
+		t.Fatal("failed to append first event", err)
+	}
+
+	er2, _ := storage.NewEventRecord(PlayerDamaged{Damage: 10})
+	er2.ActorId = aid
+	er2.ActorName = "Player"
+	er2.Version = 1
+	err = store.AddEvents(ctx, []storage.EventRecord{er2})
+	var conflict storage.ErrConcurrencyConflict
+	if !errors.As(err, &conflict) {
+		t.Fatal("expected a concurrency conflict but got", err)
+	}
+	if conflict.ActorId != aid || conflict.Version != 1 {
+		t.Error("conflict did not describe the stale append")
+	}
+
+	records, _ := store.FetchEventsSince(ctx, "Player", aid, er1.Id)
+	if len(records) != 0 {
+		t.Error("stale event should not have been stored")
+	}
+}
+
+func TestConcurrentHandlesDetectConflicts(t *testing.T) {
+	store := memory.InMemoryStorage()
+	repo := storage.GetActorRepositoryFor[Player](store)
+	repo.Handle(CreatePlayer{Name: "Bob"})
+
+	var wg sync.WaitGroup
+	results := make([]spry.Results[Player], 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = repo.Handle(DamagePlayer{Name: "Bob", Damage: 1})
+		}(i)
+	}
+	wg.Wait()
+
+	applied := 0
+	for _, r := range results {
+		if len(r.Errors) == 0 {
+			applied++
+			continue
+		}
+		var conflict storage.ErrConcurrencyConflict
+		if !errors.As(r.Errors[0], &conflict) {
+			t.Error("expected only concurrency conflicts but got", r.Errors[0])
+		}
+	}
+
+	player, _ := repo.Fetch(spry.Identifiers{"name": "Bob"})
+	if player.HitPoints != 100-applied {
+		t.Errorf("expected %d hit points but found %d", 100-applied, player.HitPoints)
+	}
+}
+
+func TestConflictingHandlesAreRetried(t *testing.T) {
+	store := memory.InMemoryStorage()
+	repo := storage.GetActorRepositoryFor[Counter](store)
+	repo.Handle(Increment{Name: "clicks"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := repo.Handle(Increment{Name: "clicks"})
+			if len(r.Errors) > 0 {
+				t.Error("expected retries to resolve the conflict but got", r.Errors[0])
+			}
+		}()
+	}
+	wg.Wait()
+
+	counter, _ := repo.Fetch(spry.Identifiers{"name": "clicks"})
+	if counter.Count != 11 {
+		t.Errorf("expected a count of %d but found %d", 11, counter.Count)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"time"
+
+	"github.com/legitbiz/spry"
+)
+
+// an actor whose commands always succeed, for exercising concurrency
+type Counter struct {
+	Name  string
+	Count int
+}
+
+func (c Counter) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": c.Name}
+}
+
+func (c Counter) GetActorMeta() spry.ActorMeta {
+	return spry.ActorMeta{
+		SnapshotFrequency:   20,
+		SnapshotDuringWrite: true,
+		RetryAttempts:       50,
+		RetryBackoff:        time.Microsecond,
+		MaxBackoff:          10 * time.Millisecond,
+		RetryJitter:         time.Millisecond,
+		SchemaVersion:       2,
+	}
+}
+
+type Increment struct {
+	Name string
+}
+
+func (command Increment) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command Increment) Handle(actor any) ([]spry.Event, []error) {
+	switch actor.(type) {
+	case Counter:
+		return []spry.Event{Incremented(command)}, nil
+	}
+	return []spry.Event{}, nil
+}
+
+type Incremented struct {
+	Name string
+}
+
+func (event Incremented) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Counter:
+		a.Name = event.Name
+		a.Count++
+	}
+	return actor
+}
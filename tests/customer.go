@@ -0,0 +1,80 @@
+package tests
+
+import "github.com/legitbiz/spry"
+
+// an actor holding personal data, which is encrypted at rest
+type Customer struct {
+	Number string
+	Email  string `spry:"pii"`
+	Orders int
+}
+
+func (c Customer) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"number": c.Number}
+}
+
+// events
+
+type CustomerRegistered struct {
+	Number string
+	Email  string `spry:"pii"`
+}
+
+func (event CustomerRegistered) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Customer:
+		a.Number = event.Number
+		a.Email = event.Email
+	}
+	return actor
+}
+
+type OrderPlaced struct {
+	Total int
+}
+
+func (event OrderPlaced) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Customer:
+		a.Orders++
+	}
+	return actor
+}
+
+// commands
+
+type RegisterCustomer struct {
+	Number string
+	Email  string `spry:"pii"`
+}
+
+func (command RegisterCustomer) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"number": command.Number}
+}
+
+func (command RegisterCustomer) Handle(actor any) ([]spry.Event, []error) {
+	var events []spry.Event
+	switch actor.(type) {
+	case Customer:
+		events = append(events, CustomerRegistered(command))
+	}
+	return events, []error{}
+}
+
+type PlaceOrder struct {
+	Number string
+	Total  int
+}
+
+func (command PlaceOrder) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"number": command.Number}
+}
+
+func (command PlaceOrder) Handle(actor any) ([]spry.Event, []error) {
+	var events []spry.Event
+	switch actor.(type) {
+	case Customer:
+		events = append(events, OrderPlaced{Total: command.Total})
+	}
+	return events, []error{}
+}
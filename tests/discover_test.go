@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/legitbiz/spry/cli/cmds"
+	"github.com/legitbiz/spry/postgres"
+)
+
+func TestDiscoverActors(t *testing.T) {
+	actors, err := cmds.DiscoverActors("github.com/legitbiz/spry/tests")
+	if err != nil {
+		t.Fatal("failed to load the package", err)
+	}
+	names := []string{}
+	found := map[string]cmds.DiscoveredActor{}
+	for _, actor := range actors {
+		names = append(names, actor.Name)
+		found[actor.Name] = actor
+	}
+	// commands, events and embedded id types carry identifiers but aren't
+	// actors; a query embedding an actor doesn't hide it
+	expected := "Counter,Customer,Motorist,Onboarding,Player,PlayerCard,PlayerRoster,PlayerSummary,Vehicle,World"
+	if strings.Join(names, ",") != expected {
+		t.Error("expected the actors, aggregates, queries and process managers", names)
+	}
+	if !found["PlayerCard"].IsQuery || !found["Onboarding"].IsProcessManager || found["Player"].IsQuery {
+		t.Error("expected queries and process managers to be told apart from actors", found)
+	}
+	motorist := found["Motorist"]
+	if !motorist.IsAggregate ||
+		len(motorist.Children) != 1 ||
+		motorist.Children[0] != "Vehicle" {
+		t.Error("expected the motorist's vehicles as its children", motorist)
+	}
+}
+
+func TestGenerateCombinedSchema(t *testing.T) {
+	schema, err := postgres.PostgresGenerateSchema("Motorist", "Vehicle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"motorist_events", "motorist_links", "vehicle_events", "vehicle_id_map"} {
+		if !strings.Contains(schema, "CREATE TABLE IF NOT EXISTS "+table+" (") {
+			t.Error("expected the schema to create", table)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func receive(t *testing.T, records <-chan storage.EventRecord, count int) []storage.EventRecord {
+	received := []storage.EventRecord{}
+	for len(received) < count {
+		select {
+		case record := <-records:
+			received = append(received, record)
+		case <-time.After(time.Second):
+			t.Fatalf("expected %d records but received %d", count, len(received))
+		}
+	}
+	return received
+}
+
+func TestSubscriptionStreamsActorEventsInOrder(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	records, _ := store.Subscribe(ctx, "Player", uuid.Nil)
+	existing := receive(t, records, 3)
+	if existing[0].Type != "PlayerCreated" ||
+		existing[1].Type != "PlayerCreated" ||
+		existing[2].Type != "PlayerDamaged" {
+		t.Fatal("records were not streamed in the order they were created")
+	}
+
+	// new events are pushed to the open subscription
+	players.Handle(HealPlayer{Name: "Alice", Health: 5})
+	live := receive(t, records, 1)
+	if live[0].Type != "PlayerHealed" {
+		t.Error("expected the new event to reach the subscription")
+	}
+
+	// resuming from a checkpoint only streams what came after it
+	resumed, _ := store.Subscribe(ctx, "Player", existing[1].Id)
+	after := receive(t, resumed, 2)
+	if after[0].Id != existing[2].Id || after[1].Id != live[0].Id {
+		t.Error("resumed subscription did not start after the checkpoint")
+	}
+}
+
+func TestSubscriptionClosesWhenCancelled(t *testing.T) {
+	store := memory.InMemoryStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errs := store.Subscribe(ctx, "Player", uuid.Nil)
+	cancel()
+
+	select {
+	case _, open := <-records:
+		if open {
+			t.Error("expected no records from an empty stream")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription did not close after cancellation")
+	}
+	if err := <-errs; err != nil {
+		t.Error("cancellation should not be reported as an error", err)
+	}
+}
+
+// an event written straight to the store, for transactions that
+// commit out of order
+func playerCreatedRecord(name string) storage.EventRecord {
+	record, _ := storage.NewEventRecord(PlayerCreated{Name: name})
+	record.ActorName = "Player"
+	record.ActorId, _ = storage.GetId()
+	return record
+}
+
+func TestSubscriptionReceivesEventsInCommitOrder(t *testing.T) {
+	store := memory.InMemoryStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the first event's id is older, but its transaction commits last
+	late, _ := store.GetContext(ctx)
+	_ = store.AddEvents(late, []storage.EventRecord{playerCreatedRecord("Bob")})
+	early, _ := store.GetContext(ctx)
+	_ = store.AddEvents(early, []storage.EventRecord{playerCreatedRecord("Alice")})
+	_ = store.Commit(early)
+
+	records, _ := store.Subscribe(ctx, "Player", uuid.Nil)
+	first := receive(t, records, 1)
+	if first[0].Data.(PlayerCreated).Name != "Alice" {
+		t.Fatal("expected only the committed event")
+	}
+
+	// rolled back events are never streamed
+	rolledBack, _ := store.GetContext(ctx)
+	_ = store.AddEvents(rolledBack, []storage.EventRecord{playerCreatedRecord("Carol")})
+	_ = store.Rollback(rolledBack)
+
+	_ = store.Commit(late)
+	second := receive(t, records, 1)
+	if second[0].Data.(PlayerCreated).Name != "Bob" {
+		t.Fatal("expected the late commit to reach the subscription")
+	}
+	select {
+	case record := <-records:
+		t.Error("did not expect another record", record)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// resuming from the late commit doesn't replay events with larger ids
+	resumed, _ := store.Subscribe(ctx, "Player", second[0].Id)
+	select {
+	case record := <-resumed:
+		t.Error("did not expect records after the checkpoint", record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestPlayerHistory(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	players.Handle(HealPlayer{Name: "Bob", Health: 5})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 20})
+
+	history, err := players.History(bob, storage.HistoryOptions{})
+	if err != nil || len(history) != 4 {
+		t.Fatal("expected every event in the player's history", history, err)
+	}
+	first := history[0]
+	if first.Type != "PlayerCreated" ||
+		first.InitiatedBy != "CreatePlayer" ||
+		first.CreatedBy != "Player" ||
+		first.CreatedOn.IsZero() {
+		t.Error("expected the event's metadata", first)
+	}
+	if event, ok := first.Event.(PlayerCreated); !ok || event.Name != "Bob" {
+		t.Error("expected the event's payload", first.Event)
+	}
+	if command, ok := first.Command.(CreatePlayer); !ok || command.Name != "Bob" {
+		t.Error("expected the command that created the player", first.Command)
+	}
+	if first.State.Name != "Bob" || first.State.HitPoints != 100 {
+		t.Error("expected the player after it was created", first.State)
+	}
+	if history[1].State.HitPoints != 90 ||
+		history[2].State.HitPoints != 95 ||
+		history[3].State.HitPoints != 75 {
+		t.Error("expected the player's state after each event")
+	}
+}
+
+func TestPlayerHistoryFiltersAndPages(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	for i := 1; i <= 5; i++ {
+		players.Handle(DamagePlayer{Name: "Bob", Damage: i})
+		players.Handle(HealPlayer{Name: "Bob", Health: 1})
+	}
+
+	damage, err := players.History(bob, storage.HistoryOptions{
+		EventTypes: []string{"PlayerDamaged"},
+		Offset:     1,
+		Limit:      2,
+	})
+	if err != nil || len(damage) != 2 {
+		t.Fatal("expected a page of damage events", damage, err)
+	}
+	second := damage[0].Command.(DamagePlayer)
+	third := damage[1].Command.(DamagePlayer)
+	if second.Damage != 2 || third.Damage != 3 {
+		t.Error("expected the second and third damage events", second, third)
+	}
+	// heals before each hit still count towards the state
+	if damage[0].State.HitPoints != 98 || damage[1].State.HitPoints != 96 {
+		t.Error("expected the state to include the events filtered out", damage[0].State, damage[1].State)
+	}
+
+	past, _ := players.History(bob, storage.HistoryOptions{Offset: 20})
+	if len(past) != 0 {
+		t.Error("expected no entries past the end of the history", past)
+	}
+	missing, err := players.History(spry.Identifiers{"name": "Nobody"}, storage.HistoryOptions{})
+	if err != nil || len(missing) != 0 {
+		t.Error("expected no history for an unknown player", missing, err)
+	}
+}
+
+func TestMotoristHistory(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	vehicles := storage.GetActorRepositoryFor[Vehicle](store)
+
+	m1id := MotoristId{License: "556677889", State: "OR"}
+	ids := spry.Identifiers{"License": m1id.License, "State": m1id.State}
+	v1id := VehicleId{VIN: "700800900"}
+
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: v1id, Color: "Green"})
+	vehicles.Handle(RepaintVehicle{VehicleId: v1id, Color: "Orange"})
+
+	history, err := motorists.History(ids, storage.HistoryOptions{})
+	if err != nil || len(history) != 2 {
+		t.Fatal("expected the events of the motorist's vehicles", history, err)
+	}
+	if _, ok := history[0].Command.(RegisterVehicle); !ok {
+		t.Error("expected the command the motorist handled", history[0].Command)
+	}
+	if _, ok := history[1].Command.(RepaintVehicle); !ok {
+		t.Error("expected the command the vehicle handled", history[1].Command)
+	}
+	if history[0].State.Vehicles[0].Color != "Green" ||
+		history[1].State.Vehicles[0].Color != "Orange" {
+		t.Error("expected the motorist after each event", history[0].State, history[1].State)
+	}
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/legitbiz/spry"
@@ -115,3 +117,24 @@ func TestAggregateHandlesCommandSuccessfully(t *testing.T) {
 		t.Error("failed to rehydrate motorist correctly")
 	}
 }
+
+func TestCancelledContextStopsHandle(t *testing.T) {
+	store := memory.InMemoryStorage()
+	repo := storage.GetActorRepositoryFor[Player](store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := repo.HandleContext(ctx, CreatePlayer{Name: "Bob"})
+	if len(results.Errors) == 0 || !errors.Is(results.Errors[0], context.Canceled) {
+		t.Fatal("expected handling to stop with the context's error")
+	}
+	if _, err := repo.FetchContext(ctx, spry.Identifiers{"name": "Bob"}); !errors.Is(err, context.Canceled) {
+		t.Error("expected fetch to stop with the context's error but got", err)
+	}
+
+	player, err := repo.FetchContext(context.Background(), spry.Identifiers{"name": "Bob"})
+	if err != nil || player.Name != "" {
+		t.Error("cancelled command should not have created the player")
+	}
+}
@@ -137,3 +137,115 @@ func (rv RegisterVehicle) Handle(actor any) ([]spry.Event, []error) {
 	}
 	return []spry.Event{}, nil
 }
+
+type VehicleSold struct {
+	spry.EventMetadata `mapstructure:",squash"`
+	MotoristId         `mapstructure:",squash"`
+	VehicleId          `mapstructure:",squash"`
+}
+
+func (vs VehicleSold) GetIdentifierSet() spry.IdentifierSet {
+	return spry.IdentifierSet{
+		"Motorist": []spry.Identifiers{vs.getIdentifiers()},
+		"Vehicle":  []spry.Identifiers{vs.GetIdentifiers()},
+	}
+}
+
+func (vs VehicleSold) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Motorist:
+		vehicles := []Vehicle{}
+		for _, v := range a.Vehicles {
+			if v.VehicleId != vs.VehicleId {
+				vehicles = append(vehicles, v)
+			}
+		}
+		a.Vehicles = vehicles
+	}
+	return actor
+}
+
+type SellVehicle struct {
+	MotoristId
+	VehicleId
+}
+
+func (sv SellVehicle) GetIdentifierSet() spry.IdentifierSet {
+	return spry.IdentifierSet{
+		"Motorist": []spry.Identifiers{sv.getIdentifiers()},
+		"Vehicle":  []spry.Identifiers{sv.GetIdentifiers()},
+	}
+}
+
+func (sv SellVehicle) Handle(actor any) ([]spry.Event, []error) {
+	switch a := actor.(type) {
+	case Motorist:
+		if !spry.ContainsChild(a.Vehicles, sv.VehicleId) {
+			return []spry.Event{}, []error{errors.New("you can't sell a vehicle you don't own")}
+		}
+		return []spry.Event{
+			VehicleSold{
+				MotoristId: sv.MotoristId,
+				VehicleId:  sv.VehicleId,
+				EventMetadata: spry.EventMetadata{
+					CreatedBy:  "Motorist",
+					CreatedFor: "Vehicle",
+					Unlinks:    "Vehicle",
+				},
+			},
+		}, nil
+	}
+	return []spry.Event{}, nil
+}
+
+type VehicleRepainted struct {
+	VehicleId `mapstructure:",squash"`
+	Color     string
+}
+
+func (vr VehicleRepainted) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Vehicle:
+		a.Color = vr.Color
+	case *Motorist:
+		for i, v := range a.Vehicles {
+			if v.VehicleId == vr.VehicleId {
+				a.Vehicles[i].Color = vr.Color
+			}
+		}
+	}
+	return actor
+}
+
+type RepaintVehicle struct {
+	VehicleId
+	Color string
+}
+
+func (rv RepaintVehicle) Handle(actor any) ([]spry.Event, []error) {
+	switch a := actor.(type) {
+	case Vehicle:
+		return []spry.Event{
+			VehicleRepainted{VehicleId: a.VehicleId, Color: rv.Color},
+		}, nil
+	}
+	return []spry.Event{}, nil
+}
+
+type RetireMotorist struct {
+	MotoristId
+}
+
+func (rm RetireMotorist) GetIdentifierSet() spry.IdentifierSet {
+	return spry.IdentifierSet{
+		"Motorist": []spry.Identifiers{rm.getIdentifiers()},
+	}
+}
+
+func (rm RetireMotorist) Handle(actor any) ([]spry.Event, []error) {
+	switch actor.(type) {
+	case Motorist:
+		return []spry.Event{spry.ActorDeleted{Reason: "motorist retired"}}, nil
+	}
+	return []spry.Event{}, nil
+}
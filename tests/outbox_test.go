@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestRelayPublishesStoredEventsOnce(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterOutbox(&memory.InMemoryOutboxStore{})
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+
+	publisher := &memory.InMemoryPublisher{}
+	relay := storage.NewRelay(store, publisher, "Player")
+
+	count, err := relay.Drain(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("expected %d events to be published but got %d (%v)", 2, count, err)
+	}
+	published := publisher.GetPublished()
+	if published[0].Type != "PlayerCreated" || published[1].Type != "PlayerDamaged" {
+		t.Error("events were not published in the order they were created")
+	}
+
+	count, _ = relay.Drain(context.Background())
+	if count != 0 {
+		t.Error("published events should not be published again")
+	}
+}
+
+func TestRelayRemovesPublishedRecords(t *testing.T) {
+	store := memory.InMemoryStorage()
+	outbox := &memory.InMemoryOutboxStore{}
+	store.RegisterOutbox(outbox)
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	relay := storage.NewRelay(store, &memory.InMemoryPublisher{}, "Player")
+	_, _ = relay.Drain(context.Background())
+	if len(outbox.Records["Player"]) != 0 {
+		t.Error("published records should be removed from the outbox")
+	}
+}
+
+func TestEventsAreNotCopiedWithoutAnOutbox(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	publisher := &memory.InMemoryPublisher{}
+	relay := storage.NewRelay(store, publisher, "Player")
+	count, err := relay.Drain(context.Background())
+	if err != nil || count != 0 {
+		t.Error("expected nothing to publish when no outbox is registered")
+	}
+
+	// events stored before registration are not copied
+	store.RegisterOutbox(&memory.InMemoryOutboxStore{})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	count, _ = relay.Drain(context.Background())
+	if count != 1 || publisher.GetPublished()[0].Type != "PlayerDamaged" {
+		t.Error("expected events stored after registration to be published")
+	}
+}
+
+func TestRelayRetriesFailedPublishes(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterOutbox(&memory.InMemoryOutboxStore{})
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	publisher := &memory.InMemoryPublisher{Fail: errors.New("broker unavailable")}
+	relay := storage.NewRelay(store, publisher, "Player")
+	relay.RetryBackoff = 0
+
+	count, err := relay.Drain(context.Background())
+	if err != nil || count != 0 {
+		t.Fatal("a failed publish should be left in the outbox")
+	}
+	pending, _ := store.FetchOutbox(context.Background(), "Player", 0)
+	if len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatal("expected the failed attempt to be recorded")
+	}
+
+	publisher.Fail = nil
+	count, _ = relay.Drain(context.Background())
+	if count != 1 || len(publisher.GetPublished()) != 1 {
+		t.Error("expected the event to be published on retry")
+	}
+}
+
+func TestWriterPublisherWritesJsonLines(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterOutbox(&memory.InMemoryOutboxStore{})
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+
+	buffer := bytes.Buffer{}
+	relay := storage.NewRelay(store, storage.NewWriterPublisher(&buffer), "Player")
+	_, _ = relay.Drain(context.Background())
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"Name":"Alice"`) {
+		t.Error("expected one line of JSON per published event")
+	}
+}
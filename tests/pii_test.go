@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func encrypted(value string) bool {
+	return value != "" && !strings.Contains(value, "@")
+}
+
+func TestPersonalDataIsEncryptedAtRest(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterKeyStore(&memory.InMemoryKeyStore{})
+	customers := storage.GetActorRepositoryFor[Customer](store)
+	customers.Handle(RegisterCustomer{Number: "001", Email: "bob@example.com"})
+
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	uid, _ := store.FetchId(ctx, "Customer", spry.Identifiers{"number": "001"})
+
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	stored, _ := stores.Events.FetchSince(ctx, "Customer", uid, uuid.Nil, stores.Primitives)
+	if email := stored[0].Data.(CustomerRegistered).Email; !encrypted(email) {
+		t.Error("expected the stored event to hold an encrypted email", email)
+	}
+	commands, _ := stores.Commands.Fetch(ctx, "Customer", []uuid.UUID{stored[0].InitiatedById}, stores.Primitives)
+	if email := commands[0].Data.(RegisterCustomer).Email; !encrypted(email) {
+		t.Error("expected the stored command to hold an encrypted email", email)
+	}
+
+	records, _ := store.FetchEventsSince(ctx, "Customer", uid, uuid.Nil)
+	if records[0].Data.(CustomerRegistered).Email != "bob@example.com" {
+		t.Error("expected the fetched event to be decrypted", records[0].Data)
+	}
+	fetched, _ := store.FetchCommands(ctx, "Customer", []uuid.UUID{stored[0].InitiatedById})
+	if fetched[0].Data.(RegisterCustomer).Email != "bob@example.com" {
+		t.Error("expected the fetched command to be decrypted", fetched[0].Data)
+	}
+}
+
+func TestDeletedKeyRedactsPersonalData(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterKeyStore(&memory.InMemoryKeyStore{})
+	customers := storage.GetActorRepositoryFor[Customer](store)
+	bob := spry.Identifiers{"number": "001"}
+
+	// enough events for a snapshot to be written
+	customers.Handle(RegisterCustomer{Number: "001", Email: "bob@example.com"})
+	for i := 0; i < 25; i++ {
+		customers.Handle(PlaceOrder{Number: "001", Total: 10})
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	uid, _ := store.FetchId(ctx, "Customer", bob)
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	stored, _ := stores.Snapshots.Fetch(ctx, "Customer", uid)
+	if !stored.IsValid() || !encrypted(stored.Data.(Customer).Email) {
+		t.Fatal("expected the stored snapshot to hold an encrypted email", stored.Data)
+	}
+	snapshot, _ := store.FetchLatestSnapshot(ctx, "Customer", uid)
+	if snapshot.Data.(Customer).Email != "bob@example.com" {
+		t.Fatal("expected the fetched snapshot to be decrypted", snapshot.Data)
+	}
+	err := store.DeleteKey(ctx, "Customer", uid)
+	if err != nil {
+		t.Fatal("failed to delete key", err)
+	}
+	_ = store.Commit(ctx)
+
+	customer, err := customers.Fetch(bob)
+	if err != nil || customer.Email != storage.Redacted || customer.Orders != 25 {
+		t.Error("expected the email to be redacted and the rest to replay", customer, err)
+	}
+
+	ctx, _ = store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	records, _ := store.FetchEventsSince(ctx, "Customer", uid, uuid.Nil)
+	if records[0].Data.(CustomerRegistered).Email != storage.Redacted {
+		t.Error("expected the event's email to be redacted", records[0].Data)
+	}
+	commands, _ := store.FetchCommands(ctx, "Customer", []uuid.UUID{records[0].InitiatedById})
+	if commands[0].Data.(RegisterCustomer).Email != storage.Redacted {
+		t.Error("expected the command's email to be redacted", commands[0].Data)
+	}
+}
+
+func TestKeysAreKeptPerActorType(t *testing.T) {
+	keys := &memory.InMemoryKeyStore{}
+	ctx := context.Background()
+	uid, _ := storage.GetId()
+	_ = keys.Add(ctx, "Customer", uid, []byte("customer"))
+	_ = keys.Add(ctx, "Player", uid, []byte("player"))
+
+	_ = keys.Delete(ctx, "Player", uid)
+	customer, _ := keys.Fetch(ctx, "Customer", uid)
+	player, _ := keys.Fetch(ctx, "Player", uid)
+	if string(customer) != "customer" || player != nil {
+		t.Error("expected deleting one actor type's key to leave the other's", customer, player)
+	}
+}
+
+func TestCommandsWithoutAnActorAreRedacted(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterKeyStore(&memory.InMemoryKeyStore{})
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	id, _ := storage.GetId()
+	err := store.AddCommand(ctx, "Customer", storage.CommandRecord{
+		Id:   id,
+		Type: "RegisterCustomer",
+		Data: RegisterCustomer{Number: "001", Email: "bob@example.com"},
+	})
+	if err != nil {
+		t.Fatal("failed to add command", err)
+	}
+	commands, _ := store.FetchCommands(ctx, "Customer", []uuid.UUID{id})
+	command := commands[0].Data.(RegisterCustomer)
+	if command.Email != storage.Redacted || command.Number != "001" {
+		t.Error("expected only the command's personal data to be dropped", command)
+	}
+}
+
+func TestPersonalDataIsStoredAsIsWithoutAKeyStore(t *testing.T) {
+	store := memory.InMemoryStorage()
+	customers := storage.GetActorRepositoryFor[Customer](store)
+	results := customers.Handle(RegisterCustomer{Number: "001", Email: "bob@example.com"})
+	if len(results.Errors) > 0 {
+		t.Fatal("expected the customer to be registered without a key store", results.Errors)
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	uid, _ := store.FetchId(ctx, "Customer", spry.Identifiers{"number": "001"})
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	stored, _ := stores.Events.FetchSince(ctx, "Customer", uid, uuid.Nil, stores.Primitives)
+	if email := stored[0].Data.(CustomerRegistered).Email; email != "bob@example.com" {
+		t.Error("expected the stored event to hold the plain email", email)
+	}
+}
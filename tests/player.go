@@ -13,6 +13,80 @@ func (w World) GetIdentifiers() spry.Identifiers {
 	return map[string]any{"name": w.Name}
 }
 
+// a query over player events
+type PlayerSummary struct {
+	Players     []string
+	DamageTaken int
+}
+
+func (s PlayerSummary) GetSources() spry.QuerySources {
+	return spry.QuerySources{
+		"Player": {"PlayerCreated", "PlayerDamaged"},
+	}
+}
+
+func (s PlayerSummary) GetActorMeta() spry.ActorMeta {
+	return spry.ActorMeta{
+		SnapshotFrequency: 2,
+	}
+}
+
+// a query that leaves its snapshot frequency to the default
+type PlayerRoster struct {
+	Players []string
+}
+
+func (r PlayerRoster) GetSources() spry.QuerySources {
+	return spry.QuerySources{
+		"Player": {"PlayerCreated"},
+	}
+}
+
+func (r PlayerRoster) GetActorMeta() spry.ActorMeta {
+	return spry.ActorMeta{}
+}
+
+// a query embedding the actor it follows, which is still an actor
+// because it declares its own identifiers
+type PlayerCard struct {
+	Player
+	TimesDamaged int
+}
+
+func (c PlayerCard) GetSources() spry.QuerySources {
+	return spry.QuerySources{
+		"Player": {"PlayerDamaged"},
+	}
+}
+
+// a process manager counting each new player
+type Onboarding struct {
+	Name  string
+	Steps int
+}
+
+func (o Onboarding) GetSources() spry.QuerySources {
+	return spry.QuerySources{
+		"Player": {"PlayerCreated"},
+	}
+}
+
+func (o Onboarding) Correlate(event spry.Event) spry.Identifiers {
+	switch e := event.(type) {
+	case PlayerCreated:
+		return spry.Identifiers{"player": e.Name}
+	}
+	return nil
+}
+
+func (o Onboarding) React(event spry.Event) []spry.Command {
+	switch event.(type) {
+	case PlayerCreated:
+		return []spry.Command{Increment{Name: "players"}}
+	}
+	return nil
+}
+
 // Player actor
 type Player struct {
 	Name      string
@@ -46,6 +120,13 @@ func (event PlayerCreated) Apply(actor any) any {
 		event.applyToPlayer(a)
 	case *World:
 		event.applyToWorld(a)
+	case *PlayerSummary:
+		a.Players = append(a.Players, event.Name)
+	case *PlayerRoster:
+		a.Players = append(a.Players, event.Name)
+	case *Onboarding:
+		a.Name = event.Name
+		a.Steps++
 	}
 	return actor
 }
@@ -58,6 +139,8 @@ func (event PlayerDamaged) Apply(actor any) any {
 	switch a := actor.(type) {
 	case *Player:
 		a.HitPoints -= event.Damage
+	case *PlayerSummary:
+		a.DamageTaken += event.Damage
 	}
 	return actor
 }
@@ -70,6 +153,21 @@ func (event PlayerHealed) Apply(actor any) any {
 	switch a := actor.(type) {
 	case *Player:
 		a.HitPoints += event.Health
+	case *PlayerSummary:
+		// not a source of the summary, so this should never run
+		a.DamageTaken -= event.Health
+	}
+	return actor
+}
+
+type PlayerRenamed struct {
+	Name string
+}
+
+func (event PlayerRenamed) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *Player:
+		a.Name = event.Name
 	}
 	return actor
 }
@@ -143,3 +241,42 @@ func (command HealPlayer) Handle(actor any) ([]spry.Event, []error) {
 	}
 	return events, []error{}
 }
+
+type RenamePlayer struct {
+	Name    string
+	NewName string
+}
+
+func (command RenamePlayer) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command RenamePlayer) Handle(actor any) ([]spry.Event, []error) {
+	var events []spry.Event
+	switch actor.(type) {
+	case Player:
+		events = append(events, PlayerRenamed{Name: command.NewName})
+	}
+	return events, []error{}
+}
+
+type DeletePlayer struct {
+	Name     string
+	FreeName bool
+}
+
+func (command DeletePlayer) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command DeletePlayer) Handle(actor any) ([]spry.Event, []error) {
+	var events []spry.Event
+	switch actor.(type) {
+	case Player:
+		events = append(events, spry.ActorDeleted{
+			Reason:          "player deleted",
+			FreeIdentifiers: command.FreeName,
+		})
+	}
+	return events, []error{}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+// a process whose reactions are always rejected
+type Welcome struct{}
+
+func (w Welcome) GetSources() spry.QuerySources {
+	return spry.QuerySources{
+		"Player": {"PlayerCreated"},
+	}
+}
+
+func (w Welcome) Correlate(event spry.Event) spry.Identifiers {
+	return spry.Identifiers{"player": event.(PlayerCreated).Name}
+}
+
+func (w Welcome) React(event spry.Event) []spry.Command {
+	return []spry.Command{AddToTally{Name: "welcomes", Amount: 0}}
+}
+
+type LenientWelcome struct {
+	Welcome
+}
+
+func (w LenientWelcome) GetActorMeta() spry.ActorMeta {
+	return spry.ActorMeta{BatchPolicy: spry.SkipRejected}
+}
+
+func getWelcomeDispatcher() storage.Dispatcher {
+	dispatcher := storage.NewDispatcher()
+	storage.RouteToActor[Tally](dispatcher, AddToTally{})
+	return dispatcher
+}
+
+func getOnboarding(store storage.Storage) storage.ProcessRepository[Onboarding] {
+	dispatcher := storage.NewDispatcher()
+	storage.RouteToActor[Counter](dispatcher, Increment{})
+	return storage.GetProcessRepositoryFor[Onboarding](store, dispatcher)
+}
+
+func TestProcessManagerDispatchesCommandsOnce(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	players.Handle(CreatePlayer{Name: "Alice"})
+
+	onboarding := getOnboarding(store)
+	read, err := onboarding.Process(context.Background())
+	if err != nil || read != 3 {
+		t.Fatalf("expected %d events to be read but got %d (%v)", 3, read, err)
+	}
+	read, _ = onboarding.Process(context.Background())
+	if read != 0 {
+		t.Error("events should not be processed twice")
+	}
+
+	counters := storage.GetActorRepositoryFor[Counter](store)
+	counter, _ := counters.Fetch(spry.Identifiers{"name": "players"})
+	if counter.Count != 2 {
+		t.Errorf("expected %d commands to be dispatched but got %d", 2, counter.Count)
+	}
+	bob, _ := onboarding.Fetch(spry.Identifiers{"player": "Bob"})
+	if bob.Name != "Bob" || bob.Steps != 1 {
+		t.Error("expected the process instance's state to be stored")
+	}
+}
+
+func TestProcessManagerRetriesFailedReactions(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	failing := &failingStorage{Storage: store, Fail: true}
+	onboarding := getOnboarding(failing)
+	if _, err := onboarding.Process(context.Background()); err == nil {
+		t.Fatal("expected the failed dispatch to fail processing")
+	}
+	bob, _ := onboarding.Fetch(spry.Identifiers{"player": "Bob"})
+	if bob.Steps != 0 {
+		t.Error("expected nothing from the failed reaction to be stored")
+	}
+
+	failing.Fail = false
+	read, err := onboarding.Process(context.Background())
+	if err != nil || read != 1 {
+		t.Fatalf("expected the event to be processed again but read %d (%v)", read, err)
+	}
+	counters := storage.GetActorRepositoryFor[Counter](store)
+	counter, _ := counters.Fetch(spry.Identifiers{"name": "players"})
+	if counter.Count != 1 {
+		t.Errorf("expected the command to be dispatched once but count was %d", counter.Count)
+	}
+}
+
+func TestProcessManagerReadsLateCommits(t *testing.T) {
+	store := memory.InMemoryStorage()
+	ctx := context.Background()
+	late, _ := store.GetContext(ctx)
+	_ = store.AddEvents(late, []storage.EventRecord{playerCreatedRecord("Bob")})
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Alice"})
+
+	onboarding := getOnboarding(store)
+	read, err := onboarding.Process(ctx)
+	if err != nil || read != 1 {
+		t.Fatalf("expected only the committed event to be read but read %d (%v)", read, err)
+	}
+	_ = store.Commit(late)
+	read, err = onboarding.Process(ctx)
+	if err != nil || read != 1 {
+		t.Fatalf("expected the late commit to be read but read %d (%v)", read, err)
+	}
+	bob, _ := onboarding.Fetch(spry.Identifiers{"player": "Bob"})
+	if bob.Steps != 1 {
+		t.Error("expected the late event to reach its process instance")
+	}
+}
+
+func TestProcessManagerRetriesRejectedReactions(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	welcome := storage.GetProcessRepositoryFor[Welcome](store, getWelcomeDispatcher())
+	for i := 0; i < 2; i++ {
+		read, err := welcome.Process(context.Background())
+		var rejected storage.ErrReactionRejected
+		if !errors.As(err, &rejected) || read != 0 {
+			t.Fatalf("expected the rejected reaction to fail the page but read %d (%v)", read, err)
+		}
+	}
+}
+
+func TestProcessManagerCanSkipRejectedReactions(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	welcome := storage.GetProcessRepositoryFor[LenientWelcome](store, getWelcomeDispatcher())
+	read, err := welcome.Process(context.Background())
+	if err != nil || read != 1 {
+		t.Fatalf("expected the rejected reaction to be skipped but read %d (%v)", read, err)
+	}
+	read, _ = welcome.Process(context.Background())
+	if read != 0 {
+		t.Error("skipped events should not be processed again")
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestQueryFoldsSourceEvents(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	summaries := storage.GetQueryRepositoryFor[PlayerSummary](store)
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 40})
+
+	summary, err := summaries.Fetch()
+	if err != nil {
+		t.Fatal("failed to fetch query", err)
+	}
+	if len(summary.Players) != 2 || summary.DamageTaken != 40 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// events after the query's snapshot are applied on top of it
+	players.Handle(HealPlayer{Name: "Bob", Health: 10})
+	players.Handle(DamagePlayer{Name: "Alice", Damage: 5})
+	players.Handle(CreatePlayer{Name: "Carol"})
+
+	summary, err = summaries.Fetch()
+	if err != nil {
+		t.Fatal("failed to fetch query", err)
+	}
+	if len(summary.Players) != 3 ||
+		summary.Players[2] != "Carol" ||
+		summary.DamageTaken != 45 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// repeated reads don't apply events twice
+	again, _ := summaries.Fetch()
+	if len(again.Players) != 3 || again.DamageTaken != 45 {
+		t.Errorf("unexpected summary %+v after re-read", again)
+	}
+}
+
+func TestQueryReadsLateCommits(t *testing.T) {
+	store := memory.InMemoryStorage()
+	late, _ := store.GetContext(context.Background())
+	_ = store.AddEvents(late, []storage.EventRecord{playerCreatedRecord("Bob")})
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(CreatePlayer{Name: "Carol"})
+
+	summaries := storage.GetQueryRepositoryFor[PlayerSummary](store)
+	summary, _ := summaries.Fetch()
+	if len(summary.Players) != 2 {
+		t.Fatalf("expected only committed events to be applied %+v", summary)
+	}
+	_ = store.Commit(late)
+	summary, _ = summaries.Fetch()
+	if len(summary.Players) != 3 || summary.Players[2] != "Bob" {
+		t.Errorf("expected the late commit to be applied last %+v", summary)
+	}
+}
+
+func TestQueryWithoutSnapshotFrequencyUsesDefault(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	rosters := storage.GetQueryRepositoryFor[PlayerRoster](store)
+	roster, err := rosters.Fetch()
+	if err != nil || len(roster.Players) != 1 {
+		t.Fatalf("unexpected roster %+v (%v)", roster, err)
+	}
+	ctx, _ := store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	id, _ := store.FetchId(ctx, "PlayerRoster", spry.Identifiers{"query": "PlayerRoster"})
+	if id != uuid.Nil {
+		t.Error("expected no snapshot before the default frequency was reached")
+	}
+}
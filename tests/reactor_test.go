@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestReactorsRunAfterCommit(t *testing.T) {
+	store := memory.InMemoryStorage()
+	reacted := [][]storage.EventRecord{}
+	store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		reacted = append(reacted, events)
+		return nil
+	})
+
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 100})
+	if len(reacted) != 2 ||
+		reacted[0][0].Type != "PlayerCreated" ||
+		len(reacted[1]) != 2 {
+		t.Error("expected the reactor to receive the events of each committed command")
+	}
+}
+
+func TestReactorFailuresAreReported(t *testing.T) {
+	store := memory.InMemoryStorage()
+	failures := []error{}
+	onError := func(err error) { failures = append(failures, err) }
+	store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		return errors.New("cache unavailable")
+	}, storage.ReactorConfig{OnError: onError})
+	store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		panic("oops")
+	}, storage.ReactorConfig{OnError: onError})
+
+	players := storage.GetActorRepositoryFor[Player](store)
+	results := players.Handle(CreatePlayer{Name: "Bob"})
+	if len(results.Errors) > 0 {
+		t.Error("reactor failures should not fail the command")
+	}
+	if len(failures) != 2 {
+		t.Errorf("expected %d failures to be reported but got %d", 2, len(failures))
+	}
+	player, _ := players.Fetch(results.Modified.GetIdentifiers())
+	if player.Name != "Bob" {
+		t.Error("expected the command to stay committed")
+	}
+}
+
+func TestPooledReactorsRunAsynchronously(t *testing.T) {
+	store := memory.InMemoryStorage()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		if ctx.Err() != nil {
+			t.Error("reactors should not be cancelled with the handle's context")
+		}
+		wg.Done()
+		return nil
+	}, storage.ReactorConfig{Workers: 2, QueueSize: 1})
+
+	players := storage.GetActorRepositoryFor[Player](store)
+	ctx, cancel := context.WithCancel(context.Background())
+	players.HandleContext(ctx, CreatePlayer{Name: "Bob"})
+	players.HandleContext(ctx, CreatePlayer{Name: "Alice"})
+	players.HandleContext(ctx, CreatePlayer{Name: "Carol"})
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for reactors to run")
+	}
+}
+
+func TestStoppingAReactorDrainsItsQueue(t *testing.T) {
+	store := memory.InMemoryStorage()
+	var lock sync.Mutex
+	reacted := 0
+	release := make(chan struct{})
+	stop := store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		<-release
+		lock.Lock()
+		defer lock.Unlock()
+		reacted++
+		return nil
+	}, storage.ReactorConfig{Workers: 1, QueueSize: 3})
+
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+	close(release)
+	stop()
+
+	lock.Lock()
+	if reacted != 2 {
+		t.Error("expected stop to wait for the queued commits", reacted)
+	}
+	lock.Unlock()
+
+	players.Handle(CreatePlayer{Name: "Carol"})
+	stop()
+	lock.Lock()
+	defer lock.Unlock()
+	if reacted != 2 {
+		t.Error("expected a stopped reactor to stop reacting", reacted)
+	}
+}
+
+func TestUnitOfWorkDefersReactors(t *testing.T) {
+	store := memory.InMemoryStorage()
+	reacted := 0
+	store.OnCommitted(func(ctx context.Context, events []storage.EventRecord) error {
+		reacted += len(events)
+		return nil
+	})
+
+	committed, _ := storage.NewUnitOfWork(context.Background(), store)
+	storage.GetActorRepositoryFor[Player](committed.Storage()).Handle(CreatePlayer{Name: "Bob"})
+	storage.GetActorRepositoryFor[Counter](committed.Storage()).Handle(Increment{Name: "logins"})
+	if reacted != 0 {
+		t.Fatal("reactors should wait for the unit of work to commit")
+	}
+	_ = committed.Commit()
+	if reacted != 2 {
+		t.Errorf("expected reactors to see %d events but saw %d", 2, reacted)
+	}
+
+	rolledBack, _ := storage.NewUnitOfWork(context.Background(), store)
+	storage.GetActorRepositoryFor[Player](rolledBack.Storage()).Handle(CreatePlayer{Name: "Alice"})
+	_ = rolledBack.Rollback()
+	if reacted != 2 {
+		t.Error("reactors should not see events from a unit that was rolled back")
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/cli/cmds"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+// stores a snapshot that disagrees with the player's events, as one
+// written by a buggy Apply would
+func addBadSnapshot(t *testing.T, store storage.Storage, ids spry.Identifiers) {
+	ctx, _ := store.GetContext(context.Background())
+	uid, _ := store.FetchId(ctx, "Player", ids)
+	records, _ := store.FetchEventsSince(ctx, "Player", uid, uuid.Nil)
+	last := records[len(records)-1]
+
+	snapshot, _ := storage.NewSnapshot(Player{Name: ids["name"].(string), HitPoints: 1})
+	snapshot.ActorId = uid
+	snapshot.EventsApplied = uint64(len(records))
+	snapshot.LastEventId = last.Id
+	snapshot.LastEventOn = last.CreatedOn
+	snapshot.Version = last.Version
+	err := store.AddSnapshot(ctx, "Player", snapshot, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Commit(ctx)
+}
+
+func TestRebuildReplacesBadSnapshots(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	addBadSnapshot(t, store, bob)
+
+	bad, _ := players.Fetch(bob)
+	if bad.HitPoints != 1 {
+		t.Fatal("expected the bad snapshot to be used", bad)
+	}
+
+	progress := 0
+	report, err := players.Rebuild(context.Background(), storage.RebuildOptions{
+		Workers:  2,
+		DryRun:   true,
+		Progress: func(done int, total int) { progress = total },
+	})
+	if err != nil || report.Rebuilt != 2 || progress != 2 {
+		t.Fatal("expected both players to be replayed", report, err)
+	}
+	if len(report.Changed) != 1 || report.Changed[0]["name"] != "Bob" {
+		t.Error("expected only Bob to change", report.Changed)
+	}
+	unchanged, _ := players.Fetch(bob)
+	if unchanged.HitPoints != 1 {
+		t.Error("expected a dry run to leave the snapshots alone", unchanged)
+	}
+
+	report, err = players.Rebuild(context.Background(), storage.RebuildOptions{Workers: 2})
+	if err != nil || len(report.Changed) != 1 {
+		t.Fatal("expected Bob to be rebuilt", report, err)
+	}
+	fixed, _ := players.Fetch(bob)
+	if fixed.HitPoints != 90 {
+		t.Error("expected the rebuilt snapshot to match the events", fixed)
+	}
+
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	snapshots := stores.Snapshots.(*memory.InMemorySnapshotStore)
+	if countSnapshots(store) != 2 || len(snapshots.Superseded) != 1 {
+		t.Error("expected one fresh snapshot per player and the bad one superseded", snapshots.Superseded)
+	}
+
+	report, _ = players.Rebuild(context.Background(), storage.RebuildOptions{DryRun: true})
+	if len(report.Changed) != 0 {
+		t.Error("expected nothing left to change", report.Changed)
+	}
+}
+
+func TestRebuildMotorists(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+
+	m1id := MotoristId{License: "556677889", State: "OR"}
+	ids := spry.Identifiers{"License": m1id.License, "State": m1id.State}
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "700800900"}, Color: "Green"})
+
+	report, err := motorists.Rebuild(context.Background(), storage.RebuildOptions{})
+	if err != nil || report.Rebuilt != 1 || len(report.Changed) != 0 {
+		t.Fatal("expected the motorist to be replayed without changes", report, err)
+	}
+	motorist, _ := motorists.Fetch(ids)
+	if len(motorist.Vehicles) != 1 || motorist.Vehicles[0].Color != "Green" {
+		t.Error("expected the rebuilt motorist to include its vehicle", motorist)
+	}
+}
+
+// an actor whose state keeps a list that starts out nil
+type playlist struct {
+	Name  string
+	Songs []string
+}
+
+func (p playlist) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": p.Name}
+}
+
+type playlistCreated struct {
+	Name string
+}
+
+func (event playlistCreated) Apply(actor any) any {
+	switch a := actor.(type) {
+	case *playlist:
+		a.Name = event.Name
+	}
+	return actor
+}
+
+type createPlaylist struct {
+	Name string
+}
+
+func (command createPlaylist) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command createPlaylist) Handle(actor any) ([]spry.Event, []error) {
+	return []spry.Event{playlistCreated(command)}, nil
+}
+
+func TestRebuildIgnoresEmptyListsInSnapshots(t *testing.T) {
+	store := memory.InMemoryStorage()
+	playlists := storage.GetActorRepositoryFor[playlist](store)
+	playlists.Handle(createPlaylist{Name: "road trip"})
+
+	// a snapshot read back from storage can hold an empty list
+	// where the replayed state has none
+	ctx, _ := store.GetContext(context.Background())
+	uid, _ := store.FetchId(ctx, "playlist", spry.Identifiers{"name": "road trip"})
+	records, _ := store.FetchEventsSince(ctx, "playlist", uid, uuid.Nil)
+	last := records[len(records)-1]
+	snapshot, _ := storage.NewSnapshot(playlist{Name: "road trip", Songs: []string{}})
+	snapshot.ActorId = uid
+	snapshot.EventsApplied = 1
+	snapshot.LastEventId = last.Id
+	snapshot.LastEventOn = last.CreatedOn
+	snapshot.Version = last.Version
+	_ = store.AddSnapshot(ctx, "playlist", snapshot, false)
+	_ = store.Commit(ctx)
+
+	report, err := playlists.Rebuild(context.Background(), storage.RebuildOptions{DryRun: true})
+	if err != nil || report.Rebuilt != 1 || len(report.Changed) != 0 {
+		t.Error("expected an empty list to match a missing one", report, err)
+	}
+}
+
+func TestRebuildCommandIsEmbeddedByApplications(t *testing.T) {
+	root := cmds.Init()
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == "rebuild" {
+			t.Fatal("expected the stock CLI to leave out the rebuild command")
+		}
+	}
+
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	addBadSnapshot(t, store, spry.Identifiers{"name": "Bob"})
+
+	cmds.RegisterRebuilder("Player", players.Rebuild)
+	rebuild := cmds.GetRebuild()
+	root.AddCommand(&rebuild)
+	out := bytes.Buffer{}
+	root.SetOut(&out)
+	root.SetArgs([]string{"rebuild", "Player", "--dry-run"})
+	err := root.Execute()
+	if err != nil || !strings.Contains(out.String(), "1 Player actors replayed, 1 would change") {
+		t.Error("expected the embedded command to run the registered rebuilder", out.String(), err)
+	}
+}
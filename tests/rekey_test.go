@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestRenamedPlayerKeepsOldNameAsAlias(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	renamed := players.Handle(RenamePlayer{Name: "Bob", NewName: "Robert"})
+	if len(renamed.Errors) > 0 {
+		t.Fatal("failed to rename player", renamed.Errors)
+	}
+	// commands can still address the player by its old name
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+
+	robert, _ := players.Fetch(spry.Identifiers{"name": "Robert"})
+	if robert.Name != "Robert" || robert.HitPoints != 90 {
+		t.Error("expected the renamed player to have taken damage", robert)
+	}
+
+	ctx, _ := store.GetContext(context.Background())
+	bobId, _ := store.FetchId(ctx, "Player", spry.Identifiers{"name": "Bob"})
+	robertId, _ := store.FetchId(ctx, "Player", spry.Identifiers{"name": "Robert"})
+	if bobId == uuid.Nil || bobId != robertId {
+		t.Error("expected both names to resolve to the same player")
+	}
+
+	history, _ := store.FetchIdHistory(ctx, "Player", robertId)
+	if len(history) != 2 ||
+		history[0].Identifiers["name"] != "Bob" ||
+		history[0].IsCurrent() ||
+		history[0].Retired ||
+		history[1].Identifiers["name"] != "Robert" ||
+		!history[1].IsCurrent() {
+		t.Error("expected the identifier history to record the rename", history)
+	}
+}
+
+func TestRetiredIdentifiersStopResolving(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	ctx, _ := store.GetContext(context.Background())
+	bob := spry.Identifiers{"name": "Bob"}
+	robert := spry.Identifiers{"name": "Robert"}
+	uid, _ := store.FetchId(ctx, "Player", bob)
+	err := store.Rekey(ctx, "Player", uid, robert, true)
+	if err != nil {
+		t.Fatal("failed to re-key player", err)
+	}
+	_ = store.Commit(ctx)
+
+	ctx, _ = store.GetContext(context.Background())
+	if id, _ := store.FetchId(ctx, "Player", bob); id != uuid.Nil {
+		t.Error("expected the retired name to no longer resolve")
+	}
+	if id, _ := store.FetchId(ctx, "Player", robert); id != uid {
+		t.Error("expected the new name to resolve to the player")
+	}
+	_ = store.Rollback(ctx)
+
+	// the retired name is free for another player
+	created := players.Handle(CreatePlayer{Name: "Bob"})
+	if created.Modified.HitPoints != 100 {
+		t.Error("expected a new player to be created with the retired name")
+	}
+	ctx, _ = store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	if id, _ := store.FetchId(ctx, "Player", bob); id == uuid.Nil || id == uid {
+		t.Error("expected the retired name to resolve to the new player")
+	}
+}
+
+func TestRekeyIsUndoneOnRollback(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+
+	bob := spry.Identifiers{"name": "Bob"}
+	ctx, _ := store.GetContext(context.Background())
+	uid, _ := store.FetchId(ctx, "Player", bob)
+	_ = store.Rekey(ctx, "Player", uid, spry.Identifiers{"name": "Robert"}, true)
+	_ = store.Rollback(ctx)
+
+	ctx, _ = store.GetContext(context.Background())
+	defer func() { _ = store.Rollback(ctx) }()
+	if id, _ := store.FetchId(ctx, "Player", bob); id != uid {
+		t.Error("expected the rolled back re-key to leave the old name in place")
+	}
+	history, _ := store.FetchIdHistory(ctx, "Player", uid)
+	if len(history) != 1 || !history[0].IsCurrent() {
+		t.Error("expected the rolled back re-key to leave the history alone", history)
+	}
+}
+
+func TestRenameToIdentifiersInUseIsRefused(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(CreatePlayer{Name: "Alice"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+
+	renamed := players.Handle(RenamePlayer{Name: "Alice", NewName: "Bob"})
+	var inUse storage.ErrIdentifiersInUse
+	if len(renamed.Errors) != 1 || !errors.As(renamed.Errors[0], &inUse) || inUse.ActorName != "Player" {
+		t.Fatal("expected the rename to be refused", renamed.Errors)
+	}
+
+	bob, _ := players.Fetch(spry.Identifiers{"name": "Bob"})
+	alice, _ := players.Fetch(spry.Identifiers{"name": "Alice"})
+	if bob.Name != "Bob" || bob.HitPoints != 90 || alice.Name != "Alice" {
+		t.Error("expected both players to keep their names", bob, alice)
+	}
+}
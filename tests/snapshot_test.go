@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+// stores a snapshot claiming Bob has 1 hit point as of his latest event
+func addBobSnapshot(t *testing.T, store storage.Storage, fingerprint string) {
+	addBobSnapshotWith(t, store, fingerprint, Player{Name: "Bob", HitPoints: 1})
+}
+
+func addBobSnapshotWith(t *testing.T, store storage.Storage, fingerprint string, data any) {
+	ctx, _ := store.GetContext(context.Background())
+	actorId, _ := store.FetchId(ctx, "Player", spry.Identifiers{"name": "Bob"})
+	events, _ := store.FetchEventsSince(ctx, "Player", actorId, uuid.Nil)
+	snapshot, err := storage.NewSnapshot(Player{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot.Data = data
+	snapshot.ActorId = actorId
+	snapshot.LastEventId = events[len(events)-1].Id
+	snapshot.Version = events[len(events)-1].Version
+	snapshot.EventsApplied = uint64(len(events))
+	if fingerprint != "" {
+		snapshot.Fingerprint = fingerprint
+	}
+	_ = store.AddSnapshot(ctx, "Player", snapshot, false)
+	_ = store.Commit(ctx)
+}
+
+func TestMatchingSnapshotIsUsed(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 40})
+	addBobSnapshot(t, store, "")
+
+	player, _ := players.Fetch(spry.Identifiers{"name": "Bob"})
+	if player.HitPoints != 1 {
+		t.Errorf("expected the snapshot to be used but hit points were %d", player.HitPoints)
+	}
+}
+
+// disk backed stores decode snapshot data without knowing the actor type
+func TestUntypedSnapshotDataIsDecodedIntoActor(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 40})
+	addBobSnapshotWith(t, store, "", map[string]any{"Name": "Bob", "HitPoints": 1.0})
+
+	player, err := players.Fetch(spry.Identifiers{"name": "Bob"})
+	if err != nil || player.Name != "Bob" || player.HitPoints != 1 {
+		t.Errorf("expected the snapshot to be decoded into a player but got %+v (%v)", player, err)
+	}
+
+	results := players.Handle(HealPlayer{Name: "Bob", Health: 10})
+	if results.Modified.HitPoints != 11 {
+		t.Errorf("expected hit points to be %d but were %d", 11, results.Modified.HitPoints)
+	}
+}
+
+func TestStaleSnapshotIsRebuiltFromEvents(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 40})
+	addBobSnapshot(t, store, "stale")
+
+	player, _ := players.Fetch(spry.Identifiers{"name": "Bob"})
+	if player.HitPoints != 60 {
+		t.Errorf("expected hit points to be rebuilt as %d but were %d", 60, player.HitPoints)
+	}
+}
+
+func TestFingerprintFollowsShapeOrSchemaVersion(t *testing.T) {
+	if storage.GetFingerprint(Player{}) != storage.GetFingerprint(Player{Name: "Bob"}) {
+		t.Error("fingerprint should not depend on the actor's values")
+	}
+	if storage.GetFingerprint(Player{}) == storage.GetFingerprint(World{}) {
+		t.Error("types with different fields should not share a fingerprint")
+	}
+	if storage.GetFingerprint(Counter{}) != "v2" {
+		t.Error("an explicit schema version should be used as the fingerprint")
+	}
+}
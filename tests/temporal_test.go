@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func countSnapshots(store storage.Storage) int {
+	stores := store.(storage.Stores[*memory.InMemoryTx])
+	snapshots := stores.Snapshots.(*memory.InMemorySnapshotStore)
+	count := 0
+	for _, list := range snapshots.Snapshots {
+		count += len(list)
+	}
+	return count
+}
+
+func TestFetchPlayerInThePast(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	between := time.Now()
+	// enough events for a snapshot to be written
+	for i := 0; i < 25; i++ {
+		players.Handle(DamagePlayer{Name: "Bob", Damage: 1})
+	}
+	written := countSnapshots(store)
+	if written == 0 {
+		t.Fatal("expected a snapshot to have been written")
+	}
+
+	before, err := players.FetchAsOf(bob, between)
+	if err != nil || before.HitPoints != 90 {
+		t.Error("expected the player as it was before the last damage", before, err)
+	}
+	atTwo, _ := players.FetchAtVersion(bob, 2)
+	atTen, _ := players.FetchAtVersion(bob, 10)
+	atLast, _ := players.FetchAtVersion(bob, 27)
+	if atTwo.HitPoints != 90 || atTen.HitPoints != 82 || atLast.HitPoints != 65 {
+		t.Error("expected the player at each version", atTwo, atTen, atLast)
+	}
+	now, _ := players.FetchAsOf(bob, time.Now())
+	if now.HitPoints != 65 {
+		t.Error("expected the current player when reading as of now", now)
+	}
+	if countSnapshots(store) != written {
+		t.Error("expected reading the past to never write snapshots")
+	}
+}
+
+func TestFetchMotoristInThePast(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	vehicles := storage.GetActorRepositoryFor[Vehicle](store)
+
+	m1id := MotoristId{License: "556677889", State: "OR"}
+	ids := spry.Identifiers{"License": m1id.License, "State": m1id.State}
+	v1id := VehicleId{VIN: "700800900"}
+
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: v1id, Color: "Green"})
+	beforeRepaint := time.Now()
+	vehicles.Handle(RepaintVehicle{VehicleId: v1id, Color: "Orange"})
+	beforeSecond := time.Now()
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "701801901"}, Color: "Gray"})
+
+	past, err := motorists.FetchAsOf(ids, beforeRepaint)
+	if err != nil || len(past.Vehicles) != 1 || past.Vehicles[0].Color != "Green" {
+		t.Error("expected the motorist before the repaint", past, err)
+	}
+	afterRepaint, err := motorists.FetchAsOf(ids, beforeSecond)
+	if err != nil || len(afterRepaint.Vehicles) != 1 || afterRepaint.Vehicles[0].Color != "Orange" {
+		t.Error("expected the motorist with its vehicle repainted", afterRepaint, err)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestDeletedPlayerCannotBeFetchedOrHandled(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	deleted := players.Handle(DeletePlayer{Name: "Bob"})
+	if len(deleted.Errors) > 0 {
+		t.Fatal("failed to delete player", deleted.Errors)
+	}
+
+	var notFound storage.ErrActorDeleted
+	_, err := players.Fetch(bob)
+	if !errors.As(err, &notFound) || notFound.ActorName != "Player" || notFound.DeletedOn.IsZero() {
+		t.Error("expected fetching a deleted player to fail with ErrActorDeleted", err)
+	}
+
+	for _, command := range []spry.Command{
+		DamagePlayer{Name: "Bob", Damage: 10},
+		CreatePlayer{Name: "Bob"},
+	} {
+		results := players.Handle(command)
+		if len(results.Errors) != 1 || !errors.As(results.Errors[0], &notFound) {
+			t.Error("expected commands for a deleted player to be refused", results.Errors)
+		}
+	}
+
+	batch := players.HandleAll(bob, HealPlayer{Name: "Bob", Health: 10})
+	if !errors.As(batch[0].Errors[0], &notFound) {
+		t.Error("expected a batch for a deleted player to be refused", batch[0].Errors)
+	}
+}
+
+func TestDeletedPlayerCanFreeItsName(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	players.Handle(DamagePlayer{Name: "Bob", Damage: 50})
+	players.Handle(DeletePlayer{Name: "Bob", FreeName: true})
+
+	player, err := players.Fetch(bob)
+	if err != nil || player.Name != "" {
+		t.Error("expected the freed name to no longer resolve to the deleted player", player, err)
+	}
+
+	created := players.Handle(CreatePlayer{Name: "Bob"})
+	if len(created.Errors) > 0 || created.Modified.HitPoints != 100 {
+		t.Error("expected a new player to take the freed name", created)
+	}
+}
+
+func TestCommandsAfterTombstoneInBatchAreRefused(t *testing.T) {
+	store := memory.InMemoryStorage()
+	players := storage.GetActorRepositoryFor[Player](store)
+	bob := spry.Identifiers{"name": "Bob"}
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	batch := players.HandleAll(
+		bob,
+		DeletePlayer{Name: "Bob"},
+		DamagePlayer{Name: "Bob", Damage: 10},
+	)
+	var notFound storage.ErrActorDeleted
+	if !errors.As(batch[1].Errors[0], &notFound) {
+		t.Error("expected the command after the tombstone to be refused", batch[1].Errors)
+	}
+
+	// the batch was aborted so the player is still alive
+	player, err := players.Fetch(bob)
+	if err != nil || player.HitPoints != 100 {
+		t.Error("expected the aborted batch to leave the player alone", player, err)
+	}
+}
+
+func TestDeletedAggregateCannotBeFetchedOrHandled(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	id := MotoristId{License: "001", State: "TN"}
+	ids := spry.Identifiers{"License": id.License, "State": id.State}
+
+	motorists.Handle(RegisterVehicle{MotoristId: id, VehicleId: VehicleId{VIN: "002"}})
+	retired := motorists.Handle(RetireMotorist{MotoristId: id})
+	if len(retired.Errors) > 0 {
+		t.Fatal("failed to retire motorist", retired.Errors)
+	}
+
+	var notFound storage.ErrActorDeleted
+	_, err := motorists.Fetch(ids)
+	if !errors.As(err, &notFound) || notFound.ActorName != "Motorist" || notFound.DeletedOn.IsZero() {
+		t.Error("expected fetching a deleted motorist to fail with ErrActorDeleted", err)
+	}
+	results := motorists.Handle(RegisterVehicle{MotoristId: id, VehicleId: VehicleId{VIN: "003"}})
+	if len(results.Errors) != 1 || !errors.As(results.Errors[0], &notFound) {
+		t.Error("expected commands for a deleted motorist to be refused", results.Errors)
+	}
+}
@@ -1,8 +1,11 @@
 package tests
 
 import (
+	"context"
 	"testing"
 
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry/memory"
 	"github.com/legitbiz/spry/storage"
 )
 
@@ -33,3 +36,67 @@ func TestCommandMapping(t *testing.T) {
 		t.Error("failed to correctly construct event")
 	}
 }
+
+func TestEventUpcasting(t *testing.T) {
+	m := storage.CreateTypeMap()
+	m.AddTypes(PlayerDamaged{})
+	// PlayerHurt{Amount} was renamed to PlayerDamaged{Damage}
+	m.AddAlias("PlayerHurt", PlayerDamaged{})
+	m.AddUpcaster("PlayerDamaged", 1, func(data map[string]any) (map[string]any, error) {
+		return map[string]any{"Damage": data["Amount"]}, nil
+	})
+	if m.GetSchemaVersion("PlayerDamaged") != 2 {
+		t.Fatal("expected one upcaster to make PlayerDamaged version 2")
+	}
+
+	record, err := m.DecodeEvent(storage.EventRecord{
+		Type:          "PlayerHurt",
+		SchemaVersion: 1,
+		Data:          map[string]any{"Amount": 15},
+	})
+	if err != nil {
+		t.Fatal("failed to decode old event", err)
+	}
+	if record.Type != "PlayerDamaged" ||
+		record.SchemaVersion != 2 ||
+		record.Data.(PlayerDamaged).Damage != 15 {
+		t.Errorf("old event was not upcast correctly: %+v", record)
+	}
+
+	current, _ := m.DecodeEvent(storage.EventRecord{
+		Type:          "PlayerDamaged",
+		SchemaVersion: 2,
+		Data:          map[string]any{"Damage": 20},
+	})
+	if current.Data.(PlayerDamaged).Damage != 20 {
+		t.Error("current events should not be upcast")
+	}
+}
+
+func TestStoredEventsRecordSchemaVersion(t *testing.T) {
+	store := memory.InMemoryStorage()
+	store.RegisterUpcaster("PlayerDamaged", 1, func(data map[string]any) (map[string]any, error) {
+		return data, nil
+	})
+	repo := storage.GetActorRepositoryFor[Player](store)
+	results := repo.Handle(CreatePlayer{Name: "Bob"})
+	repo.Handle(DamagePlayer{Name: "Bob", Damage: 5})
+
+	records, _ := store.FetchAllEventsSince(context.Background(), "Player", uuid.Nil, 0)
+	if len(records) != 2 || len(results.Errors) > 0 {
+		t.Fatal("expected two stored events")
+	}
+	if records[0].SchemaVersion != 1 || records[1].SchemaVersion != 2 {
+		t.Error("events were not stored with their type's current schema version")
+	}
+}
+
+func TestCastersDecodeIntoFreshValues(t *testing.T) {
+	m := storage.CreateTypeMap()
+	m.AddTypes(PlayerCreated{})
+	_, _ = m.AsEvent("PlayerCreated", map[string]any{"Name": "Bob"})
+	e, _ := m.AsEvent("PlayerCreated", map[string]any{})
+	if e.(PlayerCreated).Name != "" {
+		t.Error("decoding should not carry over values from previous payloads")
+	}
+}
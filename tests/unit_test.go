@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+// fails every write of events once Fail is set
+type failingStorage struct {
+	storage.Storage
+	Fail bool
+}
+
+func (s *failingStorage) AddEvents(ctx context.Context, events []storage.EventRecord) error {
+	if s.Fail {
+		return errors.New("disk full")
+	}
+	return s.Storage.AddEvents(ctx, events)
+}
+
+func TestUnitOfWorkCommitsAcrossActorTypes(t *testing.T) {
+	store := memory.InMemoryStorage()
+	unit, err := storage.NewUnitOfWork(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := storage.GetActorRepositoryFor[Player](unit.Storage())
+	counters := storage.GetActorRepositoryFor[Counter](unit.Storage())
+
+	created := players.Handle(CreatePlayer{Name: "Bob"})
+	incremented := counters.Handle(Increment{Name: "logins"})
+	if len(created.Errors) > 0 || len(incremented.Errors) > 0 {
+		t.Fatal("failed to handle commands in the unit of work")
+	}
+	// repositories in the unit see each other's writes
+	damaged := players.Handle(DamagePlayer{Name: "Bob", Damage: 10})
+	if damaged.Modified.HitPoints != 90 {
+		t.Error("expected the unit's earlier writes to be visible within it")
+	}
+
+	if err := unit.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	player, _ := storage.GetActorRepositoryFor[Player](store).Fetch(spry.Identifiers{"name": "Bob"})
+	counter, _ := storage.GetActorRepositoryFor[Counter](store).Fetch(spry.Identifiers{"name": "logins"})
+	if player.HitPoints != 90 || counter.Count != 1 {
+		t.Error("expected everything in the unit to be stored")
+	}
+	if err := unit.Commit(); !errors.Is(err, storage.ErrUnitOfWorkClosed) {
+		t.Error("a unit of work should only be committed once")
+	}
+}
+
+func TestUnitOfWorkRollbackDiscardsEverything(t *testing.T) {
+	store := memory.InMemoryStorage()
+	unit, _ := storage.NewUnitOfWork(context.Background(), store)
+	players := storage.GetActorRepositoryFor[Player](unit.Storage())
+	counters := storage.GetActorRepositoryFor[Counter](unit.Storage())
+	players.Handle(CreatePlayer{Name: "Bob"})
+	counters.Handle(Increment{Name: "logins"})
+
+	if err := unit.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	player, _ := storage.GetActorRepositoryFor[Player](store).Fetch(spry.Identifiers{"name": "Bob"})
+	counter, _ := storage.GetActorRepositoryFor[Counter](store).Fetch(spry.Identifiers{"name": "logins"})
+	if player.Name != "" || counter.Count != 0 || len(getCommands(store)) != 0 {
+		t.Error("expected nothing from the unit to be stored")
+	}
+	results := players.Handle(CreatePlayer{Name: "Alice"})
+	if len(results.Errors) == 0 {
+		t.Error("expected a closed unit of work to reject further commands")
+	}
+}
+
+func TestRepositoryFailureFailsUnitOfWork(t *testing.T) {
+	store := memory.InMemoryStorage()
+	failing := &failingStorage{Storage: store}
+	unit, _ := storage.NewUnitOfWork(context.Background(), failing)
+	players := storage.GetActorRepositoryFor[Player](unit.Storage())
+	counters := storage.GetActorRepositoryFor[Counter](unit.Storage())
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	failing.Fail = true
+	results := counters.Handle(Increment{Name: "logins"})
+	if len(results.Errors) == 0 {
+		t.Fatal("expected the failed write to be reported")
+	}
+
+	if err := unit.Commit(); !errors.Is(err, storage.ErrUnitOfWorkFailed) {
+		t.Errorf("expected the unit of work to fail but got %v", err)
+	}
+	player, _ := storage.GetActorRepositoryFor[Player](store).Fetch(spry.Identifiers{"name": "Bob"})
+	if player.Name != "" {
+		t.Error("expected writes before the failure to be rolled back")
+	}
+}
+
+// created for another actor type without saying which one
+type BadgeAwarded struct {
+	spry.EventMetadata
+	Name string
+}
+
+func (event BadgeAwarded) Apply(actor any) any {
+	return actor
+}
+
+type AwardBadge struct {
+	Name string
+}
+
+func (command AwardBadge) GetIdentifiers() spry.Identifiers {
+	return spry.Identifiers{"name": command.Name}
+}
+
+func (command AwardBadge) Handle(actor any) ([]spry.Event, []error) {
+	return []spry.Event{BadgeAwarded{
+		EventMetadata: spry.EventMetadata{CreatedFor: "Badge"},
+		Name:          command.Name,
+	}}, nil
+}
+
+func TestInvalidEventFailsUnitOfWork(t *testing.T) {
+	store := memory.InMemoryStorage()
+	unit, _ := storage.NewUnitOfWork(context.Background(), store)
+	players := storage.GetActorRepositoryFor[Player](unit.Storage())
+
+	players.Handle(CreatePlayer{Name: "Bob"})
+	results := players.Handle(AwardBadge{Name: "Bob"})
+	if len(results.Errors) == 0 {
+		t.Fatal("expected the invalid event to be reported")
+	}
+
+	if err := unit.Commit(); !errors.Is(err, storage.ErrUnitOfWorkFailed) {
+		t.Errorf("expected the unit of work to fail but got %v", err)
+	}
+}
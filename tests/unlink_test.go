@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/legitbiz/spry"
+	"github.com/legitbiz/spry/memory"
+	"github.com/legitbiz/spry/storage"
+)
+
+func TestSoldVehicleIsUnlinkedFromMotorist(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	vehicles := storage.GetActorRepositoryFor[Vehicle](store)
+
+	m1id := MotoristId{License: "112233445", State: "TX"}
+	kept := VehicleId{VIN: "100200300"}
+	sold := VehicleId{VIN: "400500600"}
+
+	for _, vid := range []VehicleId{kept, sold} {
+		r := motorists.Handle(RegisterVehicle{
+			MotoristId: m1id,
+			VehicleId:  vid,
+			Type:       "Truck",
+			Make:       "Ford",
+			Model:      "F-150",
+			Color:      "Red",
+		})
+		if len(r.Errors) > 0 {
+			t.Fatal("failed to register vehicle", r.Errors)
+		}
+	}
+
+	r := motorists.Handle(SellVehicle{MotoristId: m1id, VehicleId: sold})
+	if len(r.Errors) > 0 {
+		t.Fatal("failed to sell vehicle", r.Errors)
+	}
+	if len(r.Modified.Vehicles) != 1 || r.Modified.Vehicles[0].VehicleId != kept {
+		t.Error("expected the sold vehicle to be removed from the motorist")
+	}
+
+	for _, vid := range []VehicleId{kept, sold} {
+		r := vehicles.Handle(RepaintVehicle{VehicleId: vid, Color: "Blue"})
+		if len(r.Errors) > 0 {
+			t.Fatal("failed to repaint vehicle", r.Errors)
+		}
+	}
+
+	v, _ := vehicles.Fetch(sold.GetIdentifiers())
+	if v.Color != "Blue" {
+		t.Error("expected the sold vehicle to still handle its own commands")
+	}
+
+	m1, _ := motorists.Fetch(spry.Identifiers{"License": m1id.License, "State": m1id.State})
+	if len(m1.Vehicles) != 1 ||
+		m1.Vehicles[0].VehicleId != kept ||
+		m1.Vehicles[0].Color != "Blue" {
+		t.Error("expected the motorist to only see events from the vehicle it still owns", m1.Vehicles)
+	}
+
+	ctx := context.Background()
+	motoristId, _ := store.FetchId(ctx, "Motorist", m1id.getIdentifiers())
+	soldId, _ := store.FetchId(ctx, "Vehicle", sold.GetIdentifiers())
+	idMap, _ := store.FetchIdMap(ctx, "Motorist", motoristId)
+	if len(idMap.Aggregated["Vehicle"]) != 1 {
+		t.Error("expected only one vehicle to remain linked to the motorist")
+	}
+	if _, ok := idMap.Unlinked["Vehicle"][soldId]; !ok {
+		t.Error("expected the sold vehicle's link to be kept as history")
+	}
+}
+
+func TestRelinkedVehicleSkipsEventsFromBetweenItsLinks(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	vehicles := storage.GetActorRepositoryFor[Vehicle](store)
+	m1id := MotoristId{License: "112233445", State: "TX"}
+	m2id := MotoristId{License: "998877665", State: "TX"}
+	vid := VehicleId{VIN: "100200300"}
+
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: vid, Color: "Red"})
+	motorists.Handle(SellVehicle{MotoristId: m1id, VehicleId: vid})
+
+	// the other owner's registration and repaint happen between the links
+	r := motorists.Handle(RegisterVehicle{MotoristId: m2id, VehicleId: vid, Color: "Red"})
+	if len(r.Errors) > 0 || len(r.Modified.Vehicles) != 1 {
+		t.Fatal("expected the second owner to only see the vehicle once", r.Modified.Vehicles, r.Errors)
+	}
+	vehicles.Handle(RepaintVehicle{VehicleId: vid, Color: "Blue"})
+	motorists.Handle(SellVehicle{MotoristId: m2id, VehicleId: vid})
+
+	r = motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: vid, Color: "Green"})
+	if len(r.Errors) > 0 {
+		t.Fatal("expected the vehicle to be bought back", r.Errors)
+	}
+	vehicles.Handle(RepaintVehicle{VehicleId: vid, Color: "Yellow"})
+
+	m1, _ := motorists.Fetch(m1id.getIdentifiers())
+	if len(m1.Vehicles) != 1 || m1.Vehicles[0].Color != "Yellow" {
+		t.Error("expected only events from the vehicle's links to the motorist", m1.Vehicles)
+	}
+	m2, _ := motorists.Fetch(m2id.getIdentifiers())
+	if len(m2.Vehicles) != 0 {
+		t.Error("expected events after the second owner sold the vehicle to be excluded", m2.Vehicles)
+	}
+
+	// replaying without snapshots gives the same result
+	ctx := context.Background()
+	motoristId, _ := store.FetchId(ctx, "Motorist", m1id.getIdentifiers())
+	vehicleId, _ := store.FetchId(ctx, "Vehicle", vid.GetIdentifiers())
+	idMap, _ := store.FetchIdMap(ctx, "Motorist", motoristId)
+	if len(idMap.Periods["Vehicle"][vehicleId]) != 2 {
+		t.Error("expected both of the vehicle's links to be kept as history", idMap.Periods)
+	}
+	last := storage.CreateLastEvents()
+	last.UpdateFromMap(idMap)
+	records, _ := store.FetchAggregatedEventsSince(ctx, "Motorist", motoristId, uuid.Nil, last)
+	colors := []string{}
+	for _, record := range records {
+		if registered, ok := record.Data.(VehicleRegistered); ok {
+			colors = append(colors, registered.Color)
+		}
+		if repainted, ok := record.Data.(VehicleRepainted); ok {
+			colors = append(colors, repainted.Color)
+		}
+	}
+	if strings.Join(colors, ",") != "Red,Green,Yellow" {
+		t.Error("expected the events from between the links to be excluded", colors)
+	}
+}
+
+// an event that names a child without saying which one
+type vehicleScrapped struct {
+	spry.EventMetadata
+}
+
+func (event vehicleScrapped) Apply(actor any) any {
+	return actor
+}
+
+type scrapVehicle struct {
+	MotoristId
+	spry.EventMetadata
+}
+
+func (command scrapVehicle) GetIdentifierSet() spry.IdentifierSet {
+	return spry.IdentifierSet{"Motorist": []spry.Identifiers{command.getIdentifiers()}}
+}
+
+func (command scrapVehicle) Handle(actor any) ([]spry.Event, []error) {
+	return []spry.Event{vehicleScrapped{EventMetadata: command.EventMetadata}}, nil
+}
+
+func TestChildEventsWithoutIdentifiersAreRejected(t *testing.T) {
+	store := memory.InMemoryStorage()
+	motorists := storage.GetAggregateRepositoryFor[Motorist](store)
+	m1id := MotoristId{License: "112233445", State: "TX"}
+	motorists.Handle(RegisterVehicle{MotoristId: m1id, VehicleId: VehicleId{VIN: "100200300"}})
+
+	for _, meta := range []spry.EventMetadata{
+		{CreatedBy: "Motorist", CreatedFor: "Vehicle"},
+		{Unlinks: "Vehicle"},
+	} {
+		r := motorists.Handle(scrapVehicle{MotoristId: m1id, EventMetadata: meta})
+		if len(r.Errors) != 1 {
+			t.Error("expected an event without identifiers for its child to be rejected", meta, r.Errors)
+		}
+	}
+}